@@ -0,0 +1,131 @@
+package qb
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArrayValue is a slice bound as a single PostgreSQL array parameter; see
+// Array.
+type ArrayValue struct {
+	Elems interface{}
+}
+
+// Array wraps a slice for a PostgreSQL array column, e.g.
+// Set("tags", Array([]string{"a", "b"})) renders one placeholder bound to
+// the array. ArrayValue is a driver.Valuer producing the array's text form
+// ({"a","b"}), which PostgreSQL casts to the column type, so it passes
+// through database/sql and the execution helpers unchanged. Drivers with
+// their own array types (pq.Array, pgx) can be used instead; to do so with
+// Build, wrap the slice for that driver yourself. A nil slice binds NULL.
+func Array(slice interface{}) ArrayValue {
+	return ArrayValue{Elems: slice}
+}
+
+// Value implements driver.Valuer.
+func (a ArrayValue) Value() (driver.Value, error) {
+	rv := reflect.ValueOf(a.Elems)
+	if !rv.IsValid() || (rv.Kind() == reflect.Slice && rv.IsNil()) {
+		return nil, nil
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("qb: Array requires a slice/array, got %T", a.Elems)
+	}
+	var b strings.Builder
+	if err := writeArray(&b, rv); err != nil {
+		return nil, err
+	}
+	return b.String(), nil
+}
+
+// writeArray writes rv as a PostgreSQL array literal; nested slices become
+// nested arrays.
+func writeArray(b *strings.Builder, rv reflect.Value) error {
+	b.WriteByte('{')
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := writeArrayElem(b, rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+// writeArrayElem writes one array element: NULL, a bare number or boolean,
+// a nested array, or a double-quoted string.
+func writeArrayElem(b *strings.Builder, ev reflect.Value) error {
+	if ev.Kind() == reflect.Interface || ev.Kind() == reflect.Pointer {
+		if ev.IsNil() {
+			b.WriteString("NULL")
+			return nil
+		}
+		if ev.Kind() == reflect.Interface {
+			return writeArrayElem(b, ev.Elem())
+		}
+	}
+	if valuer, ok := ev.Interface().(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		if dv == nil {
+			b.WriteString("NULL")
+			return nil
+		}
+		return writeArrayElem(b, reflect.ValueOf(dv))
+	}
+
+	switch x := ev.Interface().(type) {
+	case string:
+		writeArrayString(b, x)
+		return nil
+	case []byte:
+		writeArrayString(b, `\x`+hex.EncodeToString(x))
+		return nil
+	case time.Time:
+		writeArrayString(b, x.Format(time.RFC3339Nano))
+		return nil
+	}
+
+	switch ev.Kind() {
+	case reflect.Pointer:
+		return writeArrayElem(b, ev.Elem())
+	case reflect.Bool:
+		if ev.Bool() {
+			b.WriteString("t")
+		} else {
+			b.WriteString("f")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.FormatInt(ev.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b.WriteString(strconv.FormatUint(ev.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		b.WriteString(strconv.FormatFloat(ev.Float(), 'g', -1, 64))
+	case reflect.Slice, reflect.Array:
+		return writeArray(b, ev)
+	default:
+		writeArrayString(b, fmt.Sprint(ev.Interface()))
+	}
+	return nil
+}
+
+// writeArrayString writes s double-quoted, escaping '"' and '\'.
+func writeArrayString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+}
@@ -0,0 +1,119 @@
+package qb
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// guardWhere is rendered in place of WHERE for guarded UPDATE/ DELETE
+// statements without conditions.
+var guardWhere = " WHERE 1=0 " + sqlComment("guarded: mising WHERE ")
+
+// sqlComment wraps text in a /* ... */ comment, escaping delimiters inside
+// so text can never terminate the comment early.
+func sqlComment(text string) string {
+	return "/*" + escapeComment(text) + "*/"
+}
+
+// escapeComment breaks up comment delimiters: "*/" would end the comment
+// early and "/*" would open a nested one on PostgreSQL. A space goes between
+// every adjacent '*' and '/', including the '*' of the surrounding
+// delimiters, in a single pass that cannot leave a new pair behind (a
+// replace-based pass turns "/*/" into "/ */").
+func escapeComment(text string) string {
+	if !strings.ContainsRune(text, '/') {
+		return text
+	}
+	var b strings.Builder
+	b.Grow(len(text) + 2)
+	prev := byte('*') // of the opening "/*"
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if (prev == '*' && c == '/') || (prev == '/' && c == '*') {
+			b.WriteByte(' ')
+		}
+		b.WriteByte(c)
+		prev = c
+	}
+	if prev == '/' { // before the closing "*/"
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// WithComment tags every statement built by qb with a leading comment of
+// sorted key=value pairs for observability, e.g.
+// WithComment(map[string]string{"service": "billing", "op": "invoice_list"})
+// renders "/* op=invoice_list,service=billing */ SELECT ...". Comment
+// delimiters in keys and values are escaped. Like the other With* options it
+// survives Reset; pass nil to remove it.
+func (qb *QueryBuilder) WithComment(kv map[string]string) *QueryBuilder {
+	qb.Comment = kv
+	return qb
+}
+
+// WithStatementTimeout annotates statements with a timeout of d. On
+// PostgreSQL and SQLite it is a statement_timeout=<ms>ms tag in the leading
+// comment (merged with WithComment tags), which is informational only: it
+// shows up in pg_stat_activity and logs but does not cancel anything. To
+// enforce it, run SET LOCAL statement_timeout inside the same transaction as
+// a separate statement; the builder deliberately never emits multiple
+// statements, which database/sql drivers may reject or mishandle. MySQL
+// SELECTs get an enforced MAX_EXECUTION_TIME(<ms>) optimizer hint instead.
+// Like the other With* options it survives Reset; pass 0 to remove it.
+func (qb *QueryBuilder) WithStatementTimeout(d time.Duration) *QueryBuilder {
+	qb.StatementTimeout = d
+	return qb
+}
+
+// leadingComment returns the WithComment/ WithStatementTimeout prefix (with
+// trailing space) or "".
+func (qb *QueryBuilder) leadingComment() string {
+	tags := qb.Comment
+	if qb.StatementTimeout > 0 && qb.dialect() != MySQL {
+		tags = cloneMap(tags)
+		if tags == nil {
+			tags = make(map[string]string, 1)
+		}
+		tags["statement_timeout"] = strconv.FormatInt(qb.StatementTimeout.Milliseconds(), 10) + "ms"
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + tags[k]
+	}
+	return sqlComment(" "+strings.Join(pairs, ",")+" ") + " "
+}
+
+// Hint adds an optimizer hint rendered as a comment right after SELECT, e.g.
+// Hint("INDEX(users idx_users_email)") renders
+// "SELECT /*+ INDEX(users idx_users_email) */ ...". Repeated calls share one
+// hint comment, as MySQL and pg_hint_plan expect. Other statements ignore
+// hints.
+func (qb *QueryBuilder) Hint(hint string) *QueryBuilder {
+	qb.Hints = append(qb.Hints, hint)
+	return qb
+}
+
+// writeHints writes the hint comment followed by a space, if any.
+func (qb *QueryBuilder) writeHints(query *strings.Builder) {
+	hints := qb.Hints
+	if qb.StatementTimeout > 0 && qb.dialect() == MySQL {
+		ms := strconv.FormatInt(qb.StatementTimeout.Milliseconds(), 10)
+		hints = append(cloneSlice(hints), "MAX_EXECUTION_TIME("+ms+")")
+	}
+	if len(hints) == 0 {
+		return
+	}
+	query.WriteString(sqlComment("+ " + strings.Join(hints, " ") + " "))
+	query.WriteString(" ")
+}
@@ -1,31 +1,92 @@
 package qb
 
+import "time"
+
 // QueryBuilder is a tiny, chainable SQL query builder that renders a SQL string
 // plus its bound parameters. It supports SELECT/ INSERT/ UPDATE/ DELETE, WHERE/IN,
 // JOINs, GROUP BY/HAVING, ORDER BY, LIMIT/OFFSET, and RETURNING.
 type QueryBuilder struct {
 	// QueryType is the kind of statement to build (SELECT/ INSERT/ UPDATE/ DELETE).
 	QueryType QueryType
+	// CTEs are the common table expressions rendered as a leading WITH
+	// clause (see With).
+	CTEs []CTE
+	// DistinctSelect renders SELECT DISTINCT.
+	DistinctSelect bool
+	// DistinctOnColumns renders SELECT DISTINCT ON (...) (PostgreSQL).
+	DistinctOnColumns []string
+	// Hints are optimizer hints rendered as /*+ ... */ after SELECT.
+	Hints []string
 	// Table is the target table name (as written into SQL).
 	Table string
+	// FromValuesTable, when set, replaces Table in FROM with a VALUES list.
+	FromValuesTable *ValuesTable
+	// FromSub and FromSubAlias, when set, replace Table in FROM with a
+	// derived table "(<FromSub>) AS FromSubAlias".
+	FromSub      *QueryBuilder
+	FromSubAlias string
+	// FromRawExpr, when set, is written verbatim as the FROM source with its
+	// '?' markers bound to FromRawArgs (see FromRaw).
+	FromRawExpr string
+	FromRawArgs []interface{}
 	// Columns holds selected columns for SELECT or is used for rendering parts that list columns.
 	Columns []string
+	// RawColumns marks Columns entries added via SelectRaw; they are never quoted.
+	RawColumns map[string]bool
+	// ColumnArgs holds the bound args of raw Columns entries added via
+	// SelectExpr, keyed by entry.
+	ColumnArgs map[string][]interface{}
 	// Conditions are the WHERE conditions for SELECT/ UPDATE/ DELETE.
 	Conditions []Condition
+	// ScopeConditions are injected filters (e.g. tenant_id) ANDed after
+	// Conditions; see Scope.
+	ScopeConditions []Condition
 	// Joins lists JOIN clauses for SELECT queries.
 	Joins []Join
 	// GroupByColumns are the columns used in GROUP BY.
 	GroupByColumns []string
+	// GroupRollup renders GROUP BY as a ROLLUP (see GroupByRollup).
+	GroupRollup bool
+	// AutoGroup derives GROUP BY from the non-aggregate selected columns
+	// when GroupByColumns is empty (see AutoGroupBy).
+	AutoGroup bool
 	// HavingConditions are the HAVING conditions applied after GROUP BY.
 	HavingConditions []Condition
 	// OrderByArr is the ORDER BY clause specification.
 	OrderByArr []OrderBy
-	// LimitInt renders as LIMIT n when > 0.
+	// LimitInt renders as LIMIT n when LimitSet is true (LIMIT 0 included).
 	LimitInt int
-	// OffsetInt renders as OFFSET n when > 0.
+	// LimitSet reports whether Limit was called; unset means no LIMIT clause.
+	LimitSet bool
+	// LimitAllSet renders LIMIT ALL on PostgreSQL when no LIMIT is set
+	// (see LimitAll).
+	LimitAllSet bool
+	// OffsetInt renders as OFFSET n when OffsetSet is true (OFFSET 0 included).
 	OffsetInt int
+	// OffsetSet reports whether Offset was called; unset means no OFFSET clause.
+	OffsetSet bool
+	// LockStrength renders a FOR <strength> row-locking clause after a SELECT
+	// (see ForUpdate); LockOf and LockWait refine it.
+	LockStrength string
+	LockOf       []string
+	LockWait     string
+	// SetOps are the UNION members combined with the SELECT (see Union).
+	SetOps []SetOp
+	// DeleteLimit caps the rows a DELETE removes when > 0 (see DeleteLimited).
+	DeleteLimit int
 	// InsertData holds column->value pairs for INSERT.
 	InsertData map[string]interface{}
+	// InsertRows holds the rows of a multi-row INSERT (see ValuesBatch);
+	// when set it takes precedence over InsertData.
+	InsertRows []map[string]interface{}
+	// InsertQuery, when set, is the SELECT whose rows are inserted:
+	// INSERT INTO t (InsertColumns) <InsertQuery> (see InsertFromSelect).
+	InsertQuery   *QueryBuilder
+	InsertColumns []string
+	// UpdateValues and UpdateKey, when set, make the UPDATE take its values
+	// from a VALUES list joined on UpdateKey (see UpdateManyFromValues).
+	UpdateValues *ValuesTable
+	UpdateKey    string
 	// UpdateData holds column->value pairs for UPDATE SET.
 	UpdateData map[string]interface{}
 	// Parameters accumulates bound values in render order.
@@ -34,14 +95,42 @@ type QueryBuilder struct {
 	PhStyle PlaceholderStyle
 	// ParamIndex tracks the next placeholder index for DollarN style.
 	ParamIndex int
+	// Dialect selects dialect-specific rendering (see WithDialect).
+	Dialect Dialect
+	// QuoteIdents enables identifier quoting at render time (see WithQuoting).
+	QuoteIdents bool
+	// TablePrefix is prepended to every table name at render time
+	// (see WithTablePrefix).
+	TablePrefix string
+	// Comment holds key=value tags rendered as a leading comment
+	// (see WithComment).
+	Comment map[string]string
+	// StatementTimeout annotates statements with a timeout
+	// (see WithStatementTimeout).
+	StatementTimeout time.Duration
+	// MissingValues controls what a ValuesBatch row renders for a column it
+	// lacks (see WithMissingValue).
+	MissingValues MissingValue
 	// ReturningColumns lists columns for RETURNING (PostgreSQL/SQLite 3.35+).
 	ReturningColumns []string
 	// GuardWrites, when true, protects UPDATE/ DELETE without WHERE
 	// rendering a safeguard: WHERE 1=0. Default is true; call Unsafe()
 	// to disable for a single query.
 	GuardWrites bool
+	// GuardDisabled turns GuardWrites off for every query of the builder's
+	// lifetime (see DisableGuard).
+	GuardDisabled bool
 	// ConflictColumns lists target columns for ON CONFLICT (col1, col2, ...).
 	ConflictColumns []string
+	// ConflictAuto takes the ON CONFLICT target from PrimaryKey at render
+	// time (see OnConflictAuto).
+	ConflictAuto bool
+	// ConflictMerge marks ConflictColumns as a cross-dialect merge key
+	// (see MergeKey): MySQL then renders ON DUPLICATE KEY UPDATE.
+	ConflictMerge bool
+	// PrimaryKey is the registered key used by OnConflictAuto
+	// (see WithPrimaryKey).
+	PrimaryKey []string
 	// ConflictConstraint sets ON CONSTRAINT <name> instead of a column list.
 	ConflictConstraint string
 	// ConflictDoNothing toggles ON CONFLICT ... DO NOTHING.
@@ -49,11 +138,30 @@ type QueryBuilder struct {
 	// ConflictUpdateSet maps columns to either a bound value or a RawExpr
 	// for ON CONFLICT ... DO UPDATE SET <col>=<value>.
 	ConflictUpdateSet map[string]interface{}
+	// ConflictUpdateOnly lists the columns OnConflictUpdate sets to their
+	// excluded value, resolved against the inserted columns at build time.
+	ConflictUpdateOnly []string
+	// Errs collects problems recorded while chaining (e.g. a disallowed sort
+	// field). Build ignores them; BuildErr reports them.
+	Errs []error
+
+	// base is the baseline recorded by Configure that Reset restores.
+	base *QueryBuilder
+
+	// quoteSet records an explicit WithQuoting call, so a sub-builder that
+	// turned quoting off does not inherit it from its parent.
+	quoteSet bool
+
+	// lastSQL and lastArgs cache the output of the most recent Build.
+	lastSQL  string
+	lastArgs []interface{}
 }
 
 // PlaceholderStyle controls how placeholders are rendered.
 //   - DollarN:    $1, $2, ... (PostgreSQL)
 //   - QuestionMark: ?         (MySQL/SQLite)
+//   - ColonN:     :1, :2, ... (Oracle)
+//   - AtPN:       @p1, @p2, ... (SQL Server)
 type PlaceholderStyle int
 
 const (
@@ -61,6 +169,40 @@ const (
 	QuestionMark PlaceholderStyle = iota
 	// DollarN uses '$1', '$2', ... placeholders (e.g., PostgreSQL).
 	DollarN
+	// ColonN uses ':1', ':2', ... placeholders (e.g., Oracle).
+	ColonN
+	// AtPN uses '@p1', '@p2', ... placeholders (e.g., SQL Server).
+	AtPN
+)
+
+// numberPrefix returns the marker in front of the placeholder number, or
+// "" for the unnumbered QuestionMark style.
+func (s PlaceholderStyle) numberPrefix() string {
+	switch s {
+	case DollarN:
+		return "$"
+	case ColonN:
+		return ":"
+	case AtPN:
+		return "@p"
+	default:
+		return ""
+	}
+}
+
+// MissingValue selects what a ValuesBatch row renders for a column another
+// row has but it lacks (see WithMissingValue).
+type MissingValue int
+
+const (
+	// MissingReported binds NULL for the missing column and reports rows
+	// with differing columns through BuildErr (the default).
+	MissingReported MissingValue = iota
+	// NullValue renders an explicit NULL, overriding the column default.
+	NullValue
+	// DefaultKeyword renders DEFAULT, so the column default applies
+	// (PostgreSQL, MySQL; SQLite has no DEFAULT inside VALUES).
+	DefaultKeyword
 )
 
 // QueryType represents the statement being built.
@@ -91,6 +233,20 @@ const (
 //	NOTNULL = "IS NOT NULL"
 //	LIKE    = "LIKE"
 //	NOTLIKE = "NOT LIKE"
+//	HASKEY  = "?"  (PostgreSQL jsonb: key exists)
+//	HASANY  = "?|" (PostgreSQL jsonb: any of the keys exists)
+//	EXISTS  = "EXISTS"     (subquery; see WhereExists)
+//	NEXISTS = "NOT EXISTS" (subquery; see WhereNotExists)
+//	DISTINCT    = "IS DISTINCT FROM"     (NULL-safe !=)
+//	NOTDISTINCT = "IS NOT DISTINCT FROM" (NULL-safe =; see WhereNullSafeEq)
+//
+// On MySQL DISTINCT and NOTDISTINCT render as NOT (col <=> ?) and col <=> ?;
+// on SQLite as IS NOT / IS, which mean the same and need no recent version;
+// on Oracle as DECODE(col, :1, 0, 1) = 1 / = 0 and on SQL Server as
+// [NOT] EXISTS (SELECT col INTERSECT SELECT @p1).
+//
+// Operators containing '?' are rendered doubled ("??", "??|") under the
+// QuestionMark style so they cannot be mistaken for placeholders.
 type Operator string
 
 const (
@@ -106,6 +262,18 @@ const (
 	NOTNULL Operator = "IS NOT NULL"
 	LIKE    Operator = "LIKE"
 	NOTLIKE Operator = "NOT LIKE"
+	HASKEY  Operator = "?"
+	HASANY  Operator = "?|"
+	EXISTS  Operator = "EXISTS"
+	NEXISTS Operator = "NOT EXISTS"
+
+	DISTINCT    Operator = "IS DISTINCT FROM"
+	NOTDISTINCT Operator = "IS NOT DISTINCT FROM"
+
+	// openParen and closeParen mark the manual grouping of WhereOpenParen/
+	// WhereCloseParen.
+	openParen  Operator = "("
+	closeParen Operator = ")"
 )
 
 // JoinType declares supported SQL JOIN types.
@@ -114,6 +282,7 @@ const (
 //	LEFT  = "LEFT JOIN"
 //	RIGHT = "RIGHT JOIN"
 //	FULL  = "FULL OUTER JOIN"
+//	CROSS = "CROSS JOIN" (no ON condition)
 type JoinType string
 
 const (
@@ -121,30 +290,92 @@ const (
 	LEFT  JoinType = "LEFT JOIN"
 	RIGHT JoinType = "RIGHT JOIN"
 	FULL  JoinType = "FULL OUTER JOIN"
+	CROSS JoinType = "CROSS JOIN"
 )
 
 // Condition represents a single boolean predicate (e.g., "age >= 18").
 // Logic indicates how it combines with the previous condition ("AND" / "OR").
+// Cast, when set, is appended to each bound placeholder ("$1::uuid"; Oracle
+// and SQL Server wrap it as CAST(:1 AS uuid)).
+// A non-nil Group makes the condition a parenthesized sub-expression of its
+// own conditions; Column/Op/Value are then ignored. Not negates the group or
+// the single predicate: "NOT (...)".
+// A non-empty Raw is rendered verbatim instead of Column/Op, with each '?'
+// bound to the next element of Value ([]interface{}); "??" is a literal '?'.
+// Func, when set, wraps the column in a SQL function: "Func(column) op $1".
+// Tuple, when set, replaces Column with the row value "(col1, col2, ...)".
 type Condition struct {
 	Column string
 	Op     Operator
 	Value  interface{}
 	Logic  string
+	Cast   string
+	Group  []Condition
+	Raw    string
+	Func   string
+	Not    bool
+	Tuple  []string
+}
+
+// CTE is one common table expression: Name AS (<Query>). Query may be a
+// SELECT or, on PostgreSQL, a data-modifying INSERT/ UPDATE/ DELETE.
+type CTE struct {
+	Name  string
+	Query *QueryBuilder
 }
 
-// Join represents a table join: "Type Table ON Condition".
+// SetOp is one set-operation member: Op (UNION, UNION ALL) and the SELECT
+// combined with the statement.
+type SetOp struct {
+	Op    string
+	Query *QueryBuilder
+}
+
+// Join represents a table join: "Type Table ON Condition" (ON is omitted
+// when Condition is empty). A non-empty Raw is rendered verbatim instead.
 type Join struct {
 	Type      JoinType
 	Table     string
 	Condition string
+	Raw       string
 }
 
-// OrderBy configures ORDER BY column and direction.
+// OrderBy configures ORDER BY column, direction and NULL placement.
+// Raw marks Column as an expression that the quoting pass leaves untouched.
 type OrderBy struct {
 	Column string
 	Desc   bool
+	Nulls  NullsOrder
+	Raw    bool
+}
+
+// NullsOrder controls NULLS FIRST/LAST in ORDER BY. It is rendered for
+// PostgreSQL and SQLite and omitted for MySQL, which lacks the syntax.
+type NullsOrder int
+
+const (
+	// NullsDefault leaves NULL placement to the database.
+	NullsDefault NullsOrder = iota
+	// NullsFirst renders NULLS FIRST.
+	NullsFirst
+	// NullsLast renders NULLS LAST.
+	NullsLast
+)
+
+// ValuesTable is a VALUES list used as a derived table:
+// (VALUES (...), (...)) AS Alias(Columns...). Row values are bound as
+// parameters; RawExpr values are inlined.
+type ValuesTable struct {
+	Alias   string
+	Columns []string
+	Rows    [][]interface{}
 }
 
 // RawExpr represents a raw SQL fragment that will be inlined as-is
 // (no placeholder binding). Use with care, e.g. Excluded("col").
 type RawExpr string
+
+// ColumnRef is a condition value naming a column rather than a bound
+// parameter, e.g. WhereColumn("o.user_id", EQ, "u.id"). It is quoted like any
+// other identifier.
+type ColumnRef string
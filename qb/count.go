@@ -0,0 +1,79 @@
+package qb
+
+// CountQuery derives a new builder that renders the total row count of qb's
+// FROM/JOIN/WHERE/GROUP BY/HAVING, dropping ORDER BY, LIMIT, OFFSET and row
+// locks (FOR UPDATE is not allowed with aggregates), e.g. for pagination:
+//   - plain queries render SELECT COUNT(*);
+//   - with joins and a key registered via WithPrimaryKey it counts base rows
+//     once, however many joined rows they have: COUNT(DISTINCT users.id);
+//   - grouped, DISTINCT and UNION queries are counted as a derived table,
+//     SELECT COUNT(*) FROM (<query>) AS qb_count, so each group counts once.
+//
+// qb itself is left untouched so it can still build the page query.
+func (qb *QueryBuilder) CountQuery() *QueryBuilder {
+	switch {
+	case len(qb.DistinctOnColumns) > 0:
+		inner := qb.countBase()
+		inner.Columns, inner.RawColumns, inner.ColumnArgs = cloneSlice(qb.DistinctOnColumns), nil, nil
+		inner.DistinctOnColumns, inner.DistinctSelect = nil, true
+		return countOver(inner)
+	case len(qb.groupByColumns()) > 0 || qb.DistinctSelect || len(qb.SetOps) > 0:
+		return countOver(qb.countBase())
+	case len(qb.Joins) > 0 && len(qb.PrimaryKey) > 0:
+		keys := qb.qualifiedKey()
+		if len(keys) == 1 {
+			return qb.deriveRawCount("COUNT(DISTINCT " + keys[0] + ")")
+		}
+		inner := qb.deriveRawCount(keys...)
+		inner.DistinctSelect = true
+		return countOver(inner)
+	default:
+		return qb.deriveRawCount("COUNT(*)")
+	}
+}
+
+// CountDistinct is like CountQuery but renders SELECT COUNT(DISTINCT column),
+// which keeps totals accurate when joins duplicate base rows. column is
+// quoted like any other identifier when quoting is enabled.
+func (qb *QueryBuilder) CountDistinct(column string) *QueryBuilder {
+	return qb.deriveRawCount("COUNT(DISTINCT " + qb.ident(column) + ")")
+}
+
+// deriveRawCount returns a count builder selecting the already rendered
+// exprs verbatim, so quoting never touches the aggregate itself.
+func (qb *QueryBuilder) deriveRawCount(exprs ...string) *QueryBuilder {
+	c := qb.countBase()
+	c.Columns, c.RawColumns, c.ColumnArgs = nil, nil, nil
+	return c.SelectRaw(exprs...)
+}
+
+// countBase returns a copy of qb without the clauses a count ignores.
+func (qb *QueryBuilder) countBase() *QueryBuilder {
+	c := qb.Clone()
+	c.QueryType = SELECT
+	c.OrderByArr = []OrderBy{}
+	c.LimitInt, c.LimitSet, c.LimitAllSet = 0, false, false
+	c.OffsetInt, c.OffsetSet = 0, false
+	c.LockStrength, c.LockOf, c.LockWait = "", nil, ""
+	return c
+}
+
+// countOver returns SELECT COUNT(*) FROM (<inner>) AS qb_count with inner's
+// builder configuration.
+func countOver(inner *QueryBuilder) *QueryBuilder {
+	outer := inner.blank()
+	return outer.FromSubquery(inner, "qb_count").SelectRaw("COUNT(*)")
+}
+
+// qualifiedKey returns the PrimaryKey columns qualified with the base
+// table's alias or name, rendered.
+func (qb *QueryBuilder) qualifiedKey() []string {
+	keys := make([]string, len(qb.PrimaryKey))
+	for i, k := range qb.PrimaryKey {
+		keys[i] = qb.ident(k)
+		if qb.Table != "" {
+			keys[i] = qb.tableRef(qb.Table) + "." + keys[i]
+		}
+	}
+	return keys
+}
@@ -0,0 +1,56 @@
+package qb
+
+import (
+	"errors"
+	"strings"
+)
+
+// With adds a common table expression, rendered before the statement as
+// WITH name AS (<sub>), ... in call order. On PostgreSQL sub may be an
+// INSERT/ UPDATE/ DELETE with RETURNING (a data-modifying CTE) whose rows the
+// main statement reads by name, e.g.
+//
+//	With("moved", NewQB().Delete("events").Where(...).Returning("*")).
+//		Insert("archive").InsertFromSelect(NewQB().Select("*").From("moved"))
+//
+// sub is rendered into qb's parameter stream at build time, ahead of the
+// main statement's parameters, and is not reset. With may be called before
+// the statement's entry point (Select/ Insert/ Update/ Delete).
+func (qb *QueryBuilder) With(name string, sub *QueryBuilder) *QueryBuilder {
+	qb.CTEs = append(qb.CTEs, CTE{Name: name, Query: sub})
+	return qb
+}
+
+// renderWith renders the WITH clause (with trailing space) or "".
+func (qb *QueryBuilder) renderWith() string {
+	if len(qb.CTEs) == 0 {
+		return ""
+	}
+	var query strings.Builder
+	query.WriteString("WITH ")
+	for i, cte := range qb.CTEs {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString(qb.ident(cte.Name))
+		query.WriteString(" AS (")
+		query.WriteString(qb.renderSub(cte.Query))
+		query.WriteString(")")
+	}
+	query.WriteString(" ")
+	return query.String()
+}
+
+// cteErrs reports problems of the CTE sub-builders; data-modifying CTEs
+// require PostgreSQL.
+func (qb *QueryBuilder) cteErrs() []error {
+	var errs []error
+	for _, cte := range qb.CTEs {
+		errs = append(errs, cte.Query.Errs...)
+		errs = append(errs, cte.Query.validate()...)
+		if cte.Query.QueryType != SELECT && qb.dialect() != Postgres {
+			errs = append(errs, errors.New("qb: data-modifying CTE "+cte.Name+" requires PostgreSQL"))
+		}
+	}
+	return errs
+}
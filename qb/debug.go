@@ -0,0 +1,122 @@
+package qb
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugSQL renders the current query with its parameters inlined as SQL
+// literals, for logging and debugging only: never execute its output, use
+// Build. Literals follow the effective dialect (e.g. TIMESTAMP '...' on
+// PostgreSQL, X'..' blobs on MySQL/SQLite); driver.Valuer values, including
+// the sql.Null* types, are rendered from their Value(). Like Fingerprint it
+// neither builds nor resets qb.
+func (qb *QueryBuilder) DebugSQL() string {
+	sql, args := qb.peek()
+	return qb.interpolate(sql, args)
+}
+
+// interpolate replaces the placeholders in sql with literals of args.
+func (qb *QueryBuilder) interpolate(sql string, args []interface{}) string {
+	prefix := qb.PhStyle.numberPrefix()
+	if prefix == "" {
+		var b strings.Builder
+		b.Grow(len(sql) + 16*len(args))
+		next := 0
+		scanMarkers(sql, b.WriteString, func() {
+			if next < len(args) {
+				b.WriteString(qb.literal(args[next]))
+			} else {
+				b.WriteByte('?')
+			}
+			next++
+		}, func() {
+			b.WriteByte('?')
+		})
+		return b.String()
+	}
+
+	return rewriteNumbered(sql, prefix, func(n int) (string, bool) {
+		if n < 1 || n > len(args) {
+			return "", false
+		}
+		return qb.literal(args[n-1]), true
+	})
+}
+
+// literal renders v as a SQL literal for the effective dialect.
+func (qb *QueryBuilder) literal(v interface{}) string {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "NULL"
+		}
+		dv, err := valuer.Value()
+		if err != nil {
+			return sqlComment(" Value() error: " + err.Error() + " ")
+		}
+		v = dv
+	}
+
+	d := qb.dialect()
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case RawExpr:
+		return string(x)
+	case string:
+		return qb.quoteString(x)
+	case []byte:
+		if d == Postgres {
+			return `'\x` + hex.EncodeToString(x) + "'"
+		}
+		return "X'" + hex.EncodeToString(x) + "'"
+	case bool:
+		if x {
+			return "TRUE"
+		}
+		return "FALSE"
+	case time.Time:
+		if d == Postgres {
+			return "TIMESTAMP '" + x.Format("2006-01-02 15:04:05.999999") + "'"
+		}
+		return "'" + x.Format("2006-01-02 15:04:05.999999") + "'"
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return "NULL"
+		}
+		return qb.literal(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if d == Postgres {
+			parts := make([]string, rv.Len())
+			for i := range parts {
+				parts[i] = qb.literal(rv.Index(i).Interface())
+			}
+			return "ARRAY[" + strings.Join(parts, ", ") + "]"
+		}
+	}
+	return qb.quoteString(fmt.Sprint(v))
+}
+
+// quoteString renders a string literal, doubling single quotes (and
+// escaping backslashes for MySQL, where they are escape characters).
+func (qb *QueryBuilder) quoteString(s string) string {
+	if qb.dialect() == MySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -2,34 +2,118 @@ package qb
 
 import "strings"
 
-// Delete starts a DELETE statement for the given table. Any prior per-query state
-// is cleared by Reset during Build; conditions can be added via Where/ OrWhere.
+// Delete starts a DELETE statement for the given table, discarding any
+// unbuilt per-query state (builder configuration is kept, see Reset);
+// conditions can be added via Where/ OrWhere.
 func (qb *QueryBuilder) Delete(table string) *QueryBuilder {
-	qb.Reset()
-
+	qb.startStatement(DELETE)
 	qb.QueryType = DELETE
 	qb.Table = table
 	return qb
 }
 
+// DeleteLimited caps the DELETE at n rows, for batch deletes. PostgreSQL,
+// SQLite and Oracle lack DELETE ... LIMIT, so the statement becomes
+// DELETE FROM t WHERE id IN (SELECT id FROM t WHERE ... LIMIT $n) RETURNING ...
+// keyed on the WithPrimaryKey columns (default "id"), with FETCH FIRST :n
+// ROWS ONLY on Oracle; OrderBy applies inside the subquery. MySQL renders
+// DELETE ... LIMIT ? directly and SQL Server DELETE TOP (@p1) FROM t ...
+// (the subquery with SELECT TOP when ordered). n is bound; the write guard
+// still applies, and n < 1 is reported by BuildErr.
+func (qb *QueryBuilder) DeleteLimited(n int) *QueryBuilder {
+	if n < 1 {
+		qb.addErr("DeleteLimited: limit must be positive, got %d", n)
+		return qb
+	}
+	qb.DeleteLimit = n
+	return qb
+}
+
 func (qb *QueryBuilder) buildDelete() (string, []interface{}) {
 	var query strings.Builder
+	guarded := len(qb.Conditions) == 0 && qb.GuardWrites
 
-	query.WriteString("DELETE FROM ")
-	query.WriteString(qb.Table)
+	query.WriteString("DELETE ")
+	if !guarded && qb.DeleteLimit > 0 && qb.dialect() == SQLServer && len(qb.OrderByArr) == 0 {
+		query.WriteString("TOP (")
+		qb.writePlaceholder(&query)
+		query.WriteString(") ")
+		qb.Parameters = append(qb.Parameters, qb.DeleteLimit)
+	}
+	query.WriteString("FROM ")
+	query.WriteString(qb.table(qb.Table))
 
-	// WHERE clause
-	if len(qb.Conditions) > 0 {
-		query.WriteString(" WHERE ")
-		qb.buildConditions(&query, qb.Conditions)
-	} else if qb.GuardWrites {
-		query.WriteString(" WHERE 1=0 /*guarded: mising WHERE */")
+	// WHERE clause (scope conditions alone do not satisfy the guard)
+	if guarded {
+		query.WriteString(guardWhere)
+	} else if qb.DeleteLimit > 0 && qb.dialect() == SQLServer && len(qb.OrderByArr) == 0 {
+		if conds := qb.whereConditions(); len(conds) > 0 {
+			query.WriteString(" WHERE ")
+			qb.buildConditions(&query, conds)
+		}
+	} else if qb.DeleteLimit > 0 && qb.dialect() != MySQL {
+		qb.writeLimitedDelete(&query)
+	} else {
+		if conds := qb.whereConditions(); len(conds) > 0 {
+			query.WriteString(" WHERE ")
+			qb.buildConditions(&query, conds)
+		}
+		if qb.DeleteLimit > 0 {
+			qb.writeOrderBy(&query)
+			query.WriteString(" LIMIT ")
+			qb.writePlaceholder(&query)
+			qb.Parameters = append(qb.Parameters, qb.DeleteLimit)
+		}
 	}
 
 	// RETURNING
-	if len(qb.ReturningColumns) > 0 {
-		query.WriteString(" RETURNING ")
-		query.WriteString(strings.Join(qb.ReturningColumns, ", "))
-	}
+	qb.renderReturning(&query)
 	return query.String(), qb.Parameters
 }
+
+// writeLimitedDelete writes WHERE key IN (SELECT key FROM table WHERE ...
+// LIMIT $n) for DeleteLimited; Oracle gets FETCH FIRST :n ROWS ONLY and SQL
+// Server SELECT TOP (@pn).
+func (qb *QueryBuilder) writeLimitedDelete(query *strings.Builder) {
+	key := qb.PrimaryKey
+	if len(key) == 0 {
+		key = []string{"id"}
+	}
+	keys := qb.identList(key)
+
+	query.WriteString(" WHERE ")
+	if len(key) > 1 {
+		query.WriteString("(" + keys + ")")
+	} else {
+		query.WriteString(keys)
+	}
+	query.WriteString(" IN (SELECT ")
+	if qb.dialect() == SQLServer {
+		query.WriteString("TOP (")
+		qb.writePlaceholder(query)
+		query.WriteString(") ")
+		qb.Parameters = append(qb.Parameters, qb.DeleteLimit)
+	}
+	query.WriteString(keys)
+	query.WriteString(" FROM ")
+	query.WriteString(qb.table(qb.Table))
+	if conds := qb.whereConditions(); len(conds) > 0 {
+		query.WriteString(" WHERE ")
+		qb.buildConditions(query, conds)
+	}
+	qb.writeOrderBy(query)
+	switch qb.dialect() {
+	case SQLServer:
+		// limited by the TOP above
+	case Oracle:
+		query.WriteString(" FETCH FIRST ")
+		qb.writePlaceholder(query)
+		query.WriteString(" ROWS ONLY")
+		qb.Parameters = append(qb.Parameters, qb.DeleteLimit)
+	default:
+		query.WriteString(" LIMIT ")
+		qb.writePlaceholder(query)
+		qb.Parameters = append(qb.Parameters, qb.DeleteLimit)
+	}
+	query.WriteString(")")
+}
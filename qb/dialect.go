@@ -0,0 +1,84 @@
+package qb
+
+// Dialect selects SQL-flavour specific rendering.
+//   - DialectAuto: inferred from the placeholder style (DollarN ⇒ Postgres,
+//     QuestionMark ⇒ MySQL). This is the default.
+//   - Postgres, MySQL, SQLite, Oracle, SQLServer: explicit choice.
+type Dialect int
+
+const (
+	// DialectAuto infers the dialect from the placeholder style.
+	DialectAuto Dialect = iota
+	// Postgres targets PostgreSQL.
+	Postgres
+	// MySQL targets MySQL/MariaDB.
+	MySQL
+	// SQLite targets SQLite.
+	SQLite
+	// Oracle targets Oracle Database 12c+: :N placeholders and standard
+	// OFFSET n ROWS FETCH NEXT m ROWS ONLY pagination instead of LIMIT.
+	// PostgreSQL-only constructs are translated (casts, NULL-safe
+	// comparisons, random order, derived table aliases) or, like RETURNING
+	// and ON CONFLICT, dropped and reported by BuildErr.
+	Oracle
+	// SQLServer targets SQL Server 2012+: @pN placeholders, SELECT TOP (n)
+	// for a bare limit and OFFSET/ FETCH pagination, which needs ORDER BY.
+	// As on Oracle, PostgreSQL-only constructs are translated or reported
+	// by BuildErr; NULLS FIRST/LAST is dropped, as on MySQL.
+	SQLServer
+)
+
+// String returns the dialect's name, as used in error messages.
+func (d Dialect) String() string {
+	switch d {
+	case Postgres:
+		return "PostgreSQL"
+	case MySQL:
+		return "MySQL"
+	case SQLite:
+		return "SQLite"
+	case Oracle:
+		return "Oracle"
+	case SQLServer:
+		return "SQL Server"
+	default:
+		return "auto"
+	}
+}
+
+// WithDialect sets the SQL dialect along with its native placeholder style
+// (DollarN for Postgres, QuestionMark for MySQL/SQLite, ColonN for Oracle,
+// AtPN for SQLServer) and resets the
+// placeholder counter. Call WithPlaceholders afterwards to override the style.
+func (qb *QueryBuilder) WithDialect(d Dialect) *QueryBuilder {
+	qb.Dialect = d
+	switch d {
+	case Postgres:
+		qb.PhStyle = DollarN
+	case MySQL, SQLite:
+		qb.PhStyle = QuestionMark
+	case Oracle:
+		qb.PhStyle = ColonN
+	case SQLServer:
+		qb.PhStyle = AtPN
+	}
+	qb.ParamIndex = 0
+	return qb
+}
+
+// dialect resolves the effective dialect, inferring it from the placeholder
+// style when none was set explicitly.
+func (qb *QueryBuilder) dialect() Dialect {
+	if qb.Dialect != DialectAuto {
+		return qb.Dialect
+	}
+	switch qb.PhStyle {
+	case DollarN:
+		return Postgres
+	case ColonN:
+		return Oracle
+	case AtPN:
+		return SQLServer
+	}
+	return MySQL
+}
@@ -0,0 +1,347 @@
+package qb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Querier is the subset of *sql.DB / *sql.Tx / *sql.Conn used by the
+// execution helpers.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Preparer is the subset of *sql.DB / *sql.Tx / *sql.Conn used by Prepare.
+type Preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Execer is the subset of *sql.DB / *sql.Tx / *sql.Conn used to run
+// statements and queries; WithTx hands one bound to the transaction to fn.
+type Execer interface {
+	Querier
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// rowScanner is the part of *sql.Rows the scan helpers rely on.
+type rowScanner interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// Get builds the query, runs it via db.QueryContext and scans the first row
+// into dest, which must be a pointer to a struct (mapped by `db` tags) or to a
+// single scannable value. It returns sql.ErrNoRows when no row is found.
+// Like every execution helper it builds with BuildErr, so a recorded or
+// strict-mode error is returned before db is used.
+func (qb *QueryBuilder) Get(ctx context.Context, db Querier, dest interface{}) error {
+	query, args, err := qb.BuildErr()
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanOne(rows, dest)
+}
+
+// SelectContext builds the query, runs it via db.QueryContext and scans every
+// row into dest, which must be a pointer to a slice of structs, struct
+// pointers, or scannable values.
+func (qb *QueryBuilder) SelectContext(ctx context.Context, db Querier, dest interface{}) error {
+	query, args, err := qb.BuildErr()
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanAll(rows, dest)
+}
+
+// Prepare builds the query and prepares it via db.PrepareContext, for
+// executing one statement many times. It returns the statement together with
+// the args of this build. The SQL is fixed by the builder's shape (columns,
+// conditions, IN-list lengths), not by the values, so re-executing with new
+// values means passing them to the statement in the same order as these
+// args. The caller closes the statement. Like Build, it resets qb.
+func (qb *QueryBuilder) Prepare(ctx context.Context, db Preparer) (*sql.Stmt, []interface{}, error) {
+	query, args, err := qb.BuildErr()
+	if err != nil {
+		return nil, nil, err
+	}
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stmt, args, nil
+}
+
+// Page runs the derived CountQuery and then the query paginated to page
+// (1-based) with perPage rows, scanning the rows into dest as SelectContext
+// does, and returns the total row count. Pass a *sql.Tx as db to read the
+// count and the page from one consistent snapshot; with a *sql.DB they run
+// as independent statements. Like Build, it resets qb.
+func (qb *QueryBuilder) Page(ctx context.Context, db Querier, dest interface{}, page, perPage int) (int64, error) {
+	defer qb.Reset()
+
+	var total int64
+	if err := qb.CountQuery().Get(ctx, db, &total); err != nil {
+		return 0, err
+	}
+	if err := qb.Clone().Paginate(page, perPage).SelectContext(ctx, db, dest); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// Exec builds the statement and runs it via db.ExecContext, for INSERT,
+// UPDATE and DELETE without RETURNING. Errors BuildErr reports (e.g. an
+// UPDATE left without assignments) are returned without running anything.
+// Like Build, it resets qb.
+func (qb *QueryBuilder) Exec(ctx context.Context, db Execer) (sql.Result, error) {
+	query, args, err := qb.BuildErr()
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+// ExecReturning builds the INSERT/ UPDATE/ DELETE with its RETURNING clause
+// (RETURNING * when none is set), runs it via db.QueryContext and scans the
+// returned rows into dest, e.g. to get a generated id or row back: a pointer
+// to a slice receives every row as with SelectContext, any other pointer the
+// first row as with Get (sql.ErrNoRows when nothing was affected). MySQL,
+// Oracle and SQL Server have no usable RETURNING, so there it fails without
+// running anything. Like Build, it resets qb.
+func (qb *QueryBuilder) ExecReturning(ctx context.Context, db Querier, dest interface{}) error {
+	switch {
+	case qb.QueryType == SELECT:
+		qb.Reset()
+		return errors.New("qb: ExecReturning requires an INSERT, UPDATE or DELETE")
+	case !qb.returningSupported():
+		d := qb.dialect()
+		qb.Reset()
+		return fmt.Errorf("qb: ExecReturning requires RETURNING, which %s does not support", d)
+	}
+	if len(qb.ReturningColumns) == 0 {
+		qb.Returning()
+	}
+
+	query, args, err := qb.BuildErr()
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if t := reflect.TypeOf(dest); t != nil && t.Kind() == reflect.Pointer &&
+		t.Elem().Kind() == reflect.Slice && t.Elem().Elem().Kind() != reflect.Uint8 {
+		return scanAll(rows, dest)
+	}
+	return scanOne(rows, dest)
+}
+
+// WithTx runs fn inside a transaction on db: it commits when fn returns nil
+// and rolls back when fn returns an error or panics (the panic is re-raised
+// after the rollback). fn's error is returned as-is; a failed Commit is
+// returned instead. Run the builders inside fn against tx, e.g.
+// qb.Exec(ctx, tx) or qb.Get(ctx, tx, &v).
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx Execer) error) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanOne(rows rowScanner, dest interface{}) error {
+	defer rows.Close()
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("qb: dest must be a non-nil pointer, got %T", dest)
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if err := scanRow(rows, cols, v.Elem()); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+func scanAll(rows rowScanner, dest interface{}) error {
+	defer rows.Close()
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("qb: dest must be a pointer to a slice, got %T", dest)
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		if err := scanRow(rows, cols, elem.Elem()); err != nil {
+			return err
+		}
+		if isPtr {
+			slice = reflect.Append(slice, elem)
+		} else {
+			slice = reflect.Append(slice, elem.Elem())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	v.Elem().Set(slice)
+	return nil
+}
+
+// scanRow scans the current row into target. Structs are filled field by
+// field using `db` tags; any other type receives the single column directly.
+func scanRow(rows rowScanner, cols []string, target reflect.Value) error {
+	if !isStructTarget(target.Type()) {
+		if len(cols) != 1 {
+			return fmt.Errorf("qb: scanning into %s requires exactly 1 column, got %d", target.Type(), len(cols))
+		}
+		return rows.Scan(target.Addr().Interface())
+	}
+
+	fields := structFields(target.Type())
+	ptrs := make([]interface{}, len(cols))
+	for i, col := range cols {
+		idx, ok := fields.index[col]
+		if !ok {
+			return fmt.Errorf("qb: missing destination field for column %q in %s", col, target.Type())
+		}
+		ptrs[i] = fieldByIndexAlloc(target, idx).Addr().Interface()
+	}
+	return rows.Scan(ptrs...)
+}
+
+// isStructTarget reports whether t should be filled field-by-field rather
+// than scanned as a single value (time.Time and sql.Scanner types are values).
+func isStructTarget(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(scannerType) && t != timeType
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex but allocates nil
+// embedded struct pointers along the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldMap maps column names to struct field index paths, in declaration order.
+type fieldMap struct {
+	columns []string
+	index   map[string][]int
+}
+
+var fieldCache sync.Map // reflect.Type -> *fieldMap
+
+// structFields returns the column mapping of a struct type. A field's column
+// is its `db` tag (`db:"-"` skips it); untagged exported fields use their
+// lower-cased name. Untagged embedded structs are flattened.
+func structFields(t reflect.Type) *fieldMap {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(*fieldMap)
+	}
+	fm := &fieldMap{index: make(map[string][]int)}
+	collectFields(t, nil, fm)
+	fieldCache.Store(t, fm)
+	return fm
+}
+
+func collectFields(t reflect.Type, parent []int, fm *fieldMap) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		idx := append(append([]int{}, parent...), i)
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && !hasTag && ft.Kind() == reflect.Struct {
+			collectFields(ft, idx, fm)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if _, dup := fm.index[name]; dup {
+			continue
+		}
+		fm.columns = append(fm.columns, name)
+		fm.index[name] = idx
+	}
+}
@@ -0,0 +1,460 @@
+package qb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockRows is an in-memory rowScanner.
+type mockRows struct {
+	cols   []string
+	data   [][]interface{}
+	pos    int
+	closed bool
+}
+
+func (m *mockRows) Columns() ([]string, error) { return m.cols, nil }
+func (m *mockRows) Next() bool                 { m.pos++; return m.pos <= len(m.data) }
+func (m *mockRows) Err() error                 { return nil }
+func (m *mockRows) Close() error               { m.closed = true; return nil }
+func (m *mockRows) Scan(dest ...interface{}) error {
+	row := m.data[m.pos-1]
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(row[i]))
+	}
+	return nil
+}
+
+// fakeCall records one statement sent through the fake driver.
+type fakeCall struct {
+	Query string
+	Args  []interface{}
+}
+
+// fakeResult is a canned result set returned for the next query.
+type fakeResult struct {
+	Cols []string
+	Rows [][]driver.Value
+}
+
+// fakeBackend is a tiny database/sql driver that records calls and replays
+// queued results, so execution helpers can be tested without a database.
+type fakeBackend struct {
+	mu        sync.Mutex
+	calls     []fakeCall
+	results   []fakeResult
+	commits   int
+	rollbacks int
+}
+
+func newFakeDB(results ...fakeResult) (*sql.DB, *fakeBackend) {
+	b := &fakeBackend{results: results}
+	return sql.OpenDB(b), b
+}
+
+func (b *fakeBackend) Connect(context.Context) (driver.Conn, error) { return &fakeConn{b: b}, nil }
+func (b *fakeBackend) Driver() driver.Driver                        { return nil }
+
+func (b *fakeBackend) record(query string, args []driver.NamedValue) fakeResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	vals := make([]interface{}, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	b.calls = append(b.calls, fakeCall{Query: query, Args: vals})
+	if len(b.results) == 0 {
+		return fakeResult{}
+	}
+	res := b.results[0]
+	b.results = b.results[1:]
+	return res
+}
+
+type fakeConn struct{ b *fakeBackend }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c: c, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return &fakeTx{b: c.b}, nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res := c.b.record(query, args)
+	return &fakeDriverRows{cols: res.Cols, rows: res.Rows}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.b.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+type fakeStmt struct {
+	c     *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.ExecContext(context.Background(), s.query, toNamed(args))
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.QueryContext(context.Background(), s.query, toNamed(args))
+}
+
+func toNamed(args []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+	return out
+}
+
+type fakeTx struct{ b *fakeBackend }
+
+func (t *fakeTx) Commit() error {
+	t.b.mu.Lock()
+	defer t.b.mu.Unlock()
+	t.b.commits++
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.b.mu.Lock()
+	defer t.b.mu.Unlock()
+	t.b.rollbacks++
+	return nil
+}
+
+type fakeDriverRows struct {
+	cols []string
+	rows [][]driver.Value
+	pos  int
+}
+
+func (r *fakeDriverRows) Columns() []string { return r.cols }
+func (r *fakeDriverRows) Close() error      { return nil }
+func (r *fakeDriverRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+type scanUser struct {
+	ID      int64  `db:"id"`
+	Name    string `db:"name"`
+	Ignored string `db:"-"`
+}
+
+func TestScanOne_MockRows(t *testing.T) {
+	rows := &mockRows{
+		cols: []string{"name", "id"},
+		data: [][]interface{}{{"Alice", int64(7)}},
+	}
+	var u scanUser
+	if err := scanOne(rows, &u); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.ID != 7 || u.Name != "Alice" {
+		t.Fatalf("unexpected struct: %#v", u)
+	}
+	if !rows.closed {
+		t.Fatalf("expected rows to be closed")
+	}
+}
+
+func TestScanOne_NoRows(t *testing.T) {
+	var u scanUser
+	err := scanOne(&mockRows{cols: []string{"id"}}, &u)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got: %v", err)
+	}
+}
+
+func TestScanAll_MockRows(t *testing.T) {
+	rows := &mockRows{
+		cols: []string{"id", "name"},
+		data: [][]interface{}{{int64(1), "A"}, {int64(2), "B"}},
+	}
+	var users []*scanUser
+	if err := scanAll(rows, &users); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(users) != 2 || users[0].Name != "A" || users[1].ID != 2 {
+		t.Fatalf("unexpected slice: %#v", users)
+	}
+}
+
+func TestScanRow_UnknownColumn(t *testing.T) {
+	rows := &mockRows{cols: []string{"email"}, data: [][]interface{}{{"x"}}}
+	var u scanUser
+	if err := scanOne(rows, &u); err == nil {
+		t.Fatalf("expected error for unmapped column")
+	}
+}
+
+func TestGetAndSelectContext(t *testing.T) {
+	db, backend := newFakeDB(
+		fakeResult{Cols: []string{"id", "name"}, Rows: [][]driver.Value{{int64(1), "Alice"}}},
+		fakeResult{Cols: []string{"id", "name"}, Rows: [][]driver.Value{{int64(1), "A"}, {int64(2), "B"}}},
+	)
+	defer db.Close()
+	ctx := context.Background()
+
+	var u scanUser
+	err := NewQB().Select("id", "name").From("users").Where("id", EQ, 1).Get(ctx, db, &u)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if u.ID != 1 || u.Name != "Alice" {
+		t.Fatalf("unexpected struct: %#v", u)
+	}
+
+	var users []scanUser
+	err = NewQB().Select("id", "name").From("users").SelectContext(ctx, db, &users)
+	if err != nil {
+		t.Fatalf("SelectContext: %v", err)
+	}
+	if len(users) != 2 || users[1].Name != "B" {
+		t.Fatalf("unexpected slice: %#v", users)
+	}
+
+	want := []fakeCall{
+		{Query: "SELECT id, name FROM users WHERE id = $1", Args: []interface{}{int64(1)}},
+		{Query: "SELECT id, name FROM users", Args: []interface{}{}},
+	}
+	if !reflect.DeepEqual(backend.calls, want) {
+		t.Fatalf("calls mismatch:\n got: %#v\nwant: %#v", backend.calls, want)
+	}
+}
+
+func TestPage_InTransaction(t *testing.T) {
+	db, backend := newFakeDB(
+		fakeResult{Cols: []string{"count"}, Rows: [][]driver.Value{{int64(42)}}},
+		fakeResult{Cols: []string{"id", "name"}, Rows: [][]driver.Value{{int64(11), "K"}, {int64(12), "L"}}},
+	)
+	defer db.Close()
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	var users []scanUser
+	b := NewQB().Select("id", "name").From("users").Where("active", EQ, true).OrderBy("id")
+	total, err := b.Page(ctx, tx, &users, 3, 5)
+	if err != nil {
+		t.Fatalf("Page: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if total != 42 || len(users) != 2 || users[0].ID != 11 {
+		t.Fatalf("unexpected result: total=%d users=%#v", total, users)
+	}
+	want := []fakeCall{
+		{Query: "SELECT COUNT(*) FROM users WHERE active = $1", Args: []interface{}{true}},
+		{Query: "SELECT id, name FROM users WHERE active = $1 ORDER BY id ASC LIMIT 5 OFFSET 10", Args: []interface{}{true}},
+	}
+	if !reflect.DeepEqual(backend.calls, want) {
+		t.Fatalf("calls mismatch:\n got: %#v\nwant: %#v", backend.calls, want)
+	}
+	if backend.commits != 1 {
+		t.Fatalf("expected one commit, got %d", backend.commits)
+	}
+}
+
+func TestPrepare_ReusesTemplate(t *testing.T) {
+	db, backend := newFakeDB(
+		fakeResult{Cols: []string{"id", "name"}, Rows: [][]driver.Value{{int64(1), "A"}}},
+		fakeResult{Cols: []string{"id", "name"}, Rows: [][]driver.Value{{int64(2), "B"}}},
+	)
+	defer db.Close()
+	ctx := context.Background()
+
+	stmt, args, err := NewQB().Select("id", "name").From("users").Where("id", EQ, int64(1)).Prepare(ctx, db)
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	defer stmt.Close()
+	if !reflect.DeepEqual(args, []interface{}{int64(1)}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	for _, id := range []int64{1, 2} {
+		rows, err := stmt.QueryContext(ctx, id)
+		if err != nil {
+			t.Fatalf("QueryContext: %v", err)
+		}
+		rows.Close()
+	}
+
+	want := []fakeCall{
+		{Query: "SELECT id, name FROM users WHERE id = $1", Args: []interface{}{int64(1)}},
+		{Query: "SELECT id, name FROM users WHERE id = $1", Args: []interface{}{int64(2)}},
+	}
+	if !reflect.DeepEqual(backend.calls, want) {
+		t.Fatalf("calls mismatch:\n got: %#v\nwant: %#v", backend.calls, want)
+	}
+}
+
+func TestWithTx_CommitOnSuccess(t *testing.T) {
+	db, backend := newFakeDB()
+	defer db.Close()
+	ctx := context.Background()
+
+	err := WithTx(ctx, db, func(tx Execer) error {
+		_, err := NewQB().Update("users").SetUpdate("name", "A").Where("id", EQ, int64(1)).Exec(ctx, tx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	want := []fakeCall{{Query: "UPDATE users SET name = $1 WHERE id = $2", Args: []interface{}{"A", int64(1)}}}
+	if !reflect.DeepEqual(backend.calls, want) {
+		t.Fatalf("calls mismatch:\n got: %#v\nwant: %#v", backend.calls, want)
+	}
+	if backend.commits != 1 || backend.rollbacks != 0 {
+		t.Fatalf("expected commit only, got commits=%d rollbacks=%d", backend.commits, backend.rollbacks)
+	}
+}
+
+func TestWithTx_RollbackOnErrorAndPanic(t *testing.T) {
+	db, backend := newFakeDB()
+	defer db.Close()
+	ctx := context.Background()
+
+	boom := errors.New("boom")
+	err := WithTx(ctx, db, func(tx Execer) error {
+		if _, err := NewQB().Delete("users").Where("id", EQ, int64(1)).Exec(ctx, tx); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	if backend.commits != 0 || backend.rollbacks != 1 {
+		t.Fatalf("expected rollback only, got commits=%d rollbacks=%d", backend.commits, backend.rollbacks)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected the panic to be re-raised")
+			}
+		}()
+		_ = WithTx(ctx, db, func(Execer) error { panic("oops") })
+	}()
+	if backend.commits != 0 || backend.rollbacks != 2 {
+		t.Fatalf("expected a second rollback after panic, got commits=%d rollbacks=%d", backend.commits, backend.rollbacks)
+	}
+}
+
+func TestExecHelpers_ValidateBeforeRunning(t *testing.T) {
+	db, backend := newFakeDB()
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := NewQB().Update("users").SetUpdateIf(false, "name", "x").Where("id", EQ, 1).Exec(ctx, db); err == nil {
+		t.Fatalf("Exec: expected error for UPDATE without assignments")
+	}
+	if _, _, err := NewQB().Update("users").SetUpdateIf(false, "name", "x").Prepare(ctx, db); err == nil {
+		t.Fatalf("Prepare: expected error for UPDATE without assignments")
+	}
+
+	failing := func() *QueryBuilder {
+		q := NewQB().Select("id", "name").From("users")
+		q.addErr("bad input")
+		return q
+	}
+	var u scanUser
+	if err := failing().Get(ctx, db, &u); err == nil || !strings.Contains(err.Error(), "bad input") {
+		t.Fatalf("Get: expected recorded error, got: %v", err)
+	}
+	var users []scanUser
+	if err := failing().SelectContext(ctx, db, &users); err == nil {
+		t.Fatalf("SelectContext: expected recorded error")
+	}
+	if _, err := failing().Page(ctx, db, &users, 1, 10); err == nil {
+		t.Fatalf("Page: expected recorded error")
+	}
+	if err := NewQB().Update("users").SetUpdateIf(false, "name", "x").ExecReturning(ctx, db, &u); err == nil {
+		t.Fatalf("ExecReturning: expected error for UPDATE without assignments")
+	}
+
+	if len(backend.calls) != 0 {
+		t.Fatalf("nothing should reach the database, got: %#v", backend.calls)
+	}
+}
+
+func TestExecReturning_InsertReturningID(t *testing.T) {
+	db, backend := newFakeDB(
+		fakeResult{Cols: []string{"id", "name"}, Rows: [][]driver.Value{{int64(42), "Ann"}}},
+		fakeResult{Cols: []string{"id"}, Rows: [][]driver.Value{{int64(3)}, {int64(4)}}},
+	)
+	defer db.Close()
+	ctx := context.Background()
+
+	var u scanUser
+	if err := NewQB().Insert("users").Set("name", "Ann").ExecReturning(ctx, db, &u); err != nil {
+		t.Fatalf("ExecReturning: %v", err)
+	}
+	if u.ID != 42 || u.Name != "Ann" {
+		t.Fatalf("unexpected struct: %#v", u)
+	}
+
+	var ids []int64
+	err := NewQB().Delete("users").Where("active", EQ, false).Returning("id").ExecReturning(ctx, db, &ids)
+	if err != nil {
+		t.Fatalf("ExecReturning: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []int64{3, 4}) {
+		t.Fatalf("unexpected ids: %#v", ids)
+	}
+
+	want := []fakeCall{
+		{Query: "INSERT INTO users (name) VALUES ($1) RETURNING *", Args: []interface{}{"Ann"}},
+		{Query: "DELETE FROM users WHERE active = $1 RETURNING id", Args: []interface{}{false}},
+	}
+	if !reflect.DeepEqual(backend.calls, want) {
+		t.Fatalf("calls mismatch:\n got: %#v\nwant: %#v", backend.calls, want)
+	}
+
+	err = NewQB().WithDialect(MySQL).Insert("users").Set("name", "Ann").ExecReturning(ctx, db, &u)
+	if err == nil || !strings.Contains(err.Error(), "MySQL") {
+		t.Fatalf("expected MySQL error, got: %v", err)
+	}
+	if len(backend.calls) != 2 {
+		t.Fatalf("nothing should run on MySQL, got %d calls", len(backend.calls))
+	}
+
+	err = NewQB().WithDialect(Oracle).Insert("users").Set("name", "Ann").ExecReturning(ctx, db, &u)
+	if err == nil || !strings.Contains(err.Error(), "Oracle does not support") {
+		t.Fatalf("expected Oracle error, got: %v", err)
+	}
+	if len(backend.calls) != 2 {
+		t.Fatalf("nothing should run on Oracle, got %d calls", len(backend.calls))
+	}
+
+	err = NewQB().WithDialect(SQLServer).Delete("users").Where("id", EQ, 1).ExecReturning(ctx, db, &u)
+	if err == nil || !strings.Contains(err.Error(), "SQL Server does not support") {
+		t.Fatalf("expected SQL Server error, got: %v", err)
+	}
+	if len(backend.calls) != 2 {
+		t.Fatalf("nothing should run on SQL Server, got %d calls", len(backend.calls))
+	}
+}
@@ -0,0 +1,91 @@
+package qb
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Fingerprint returns a stable cache key for the current query: the SHA-256
+// hex digest of the SQL template plus a normalized encoding of its
+// parameters. It does not build or reset qb.
+//
+// Parameters are normalized by kind, so int(5) and int64(5) (and any other
+// signed integer type) hash alike, as do unsigned integers with each other
+// and float32/float64; times are compared as UTC instants. Values of
+// different kinds never collide ("5" vs 5). Pointers are hashed by the value
+// they point to (nil ones as NULL) and driver.Valuer implementations by the
+// value they return, as the driver would bind them; other values (structs,
+// slices) are encoded with %#v.
+func (qb *QueryBuilder) Fingerprint() string {
+	sql, args := qb.peek()
+
+	h := sha256.New()
+	writeField(h, "q", sql)
+	for _, a := range args {
+		writeParam(h, a)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField writes a tagged, length-prefixed field so adjacent fields can
+// never run into each other.
+func writeField(h hash.Hash, tag, s string) {
+	h.Write([]byte(tag + strconv.Itoa(len(s)) + ":" + s))
+}
+
+// writeParam writes the normalized encoding of a single parameter.
+func writeParam(h hash.Hash, v interface{}) {
+	switch x := paramValue(v).(type) {
+	case nil:
+		writeField(h, "n", "")
+	case string:
+		writeField(h, "s", x)
+	case []byte:
+		writeField(h, "b", string(x))
+	case bool:
+		writeField(h, "t", strconv.FormatBool(x))
+	case time.Time:
+		writeField(h, "d", x.UTC().Format(time.RFC3339Nano))
+	case RawExpr:
+		writeField(h, "r", string(x))
+	default:
+		rv := reflect.ValueOf(x)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			writeField(h, "i", strconv.FormatInt(rv.Int(), 10))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			writeField(h, "u", strconv.FormatUint(rv.Uint(), 10))
+		case reflect.Float32, reflect.Float64:
+			writeField(h, "f", strconv.FormatFloat(rv.Float(), 'g', -1, 64))
+		default:
+			writeField(h, "v", fmt.Sprintf("%#v", x))
+		}
+	}
+}
+
+// paramValue resolves v to the value a driver would bind: driver.Valuer
+// implementations yield their Value and pointers their target, so two
+// distinct pointers to equal values resolve alike.
+func paramValue(v interface{}) interface{} {
+	for {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil
+		}
+		if vr, ok := v.(driver.Valuer); ok {
+			if dv, err := vr.Value(); err == nil {
+				return dv
+			}
+		}
+		if rv.Kind() != reflect.Pointer {
+			return v
+		}
+		v = rv.Elem().Interface()
+	}
+}
@@ -1,11 +1,132 @@
 package qb
 
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
 // GroupBy appends columns to GROUP BY.
 func (qb *QueryBuilder) GroupBy(columns ...string) *QueryBuilder {
 	qb.GroupByColumns = append(qb.GroupByColumns, columns...)
 	return qb
 }
 
+// GroupByRollup appends columns to GROUP BY and turns the clause into a
+// ROLLUP, producing subtotal and grand-total rows: "GROUP BY ROLLUP (a, b)",
+// or "GROUP BY a, b WITH ROLLUP" on MySQL. SQLite has no ROLLUP; BuildErr
+// reports it there. Use SelectGrouping to tell subtotal rows apart.
+func (qb *QueryBuilder) GroupByRollup(columns ...string) *QueryBuilder {
+	qb.GroupByColumns = append(qb.GroupByColumns, columns...)
+	qb.GroupRollup = true
+	return qb
+}
+
+// SelectGrouping appends "GROUPING(column) AS alias" to the SELECT list; it
+// is 1 on rows where column was rolled up (subtotals) and 0 on detail rows.
+// Only meaningful with GroupByRollup. column is not quoted. SQLite lacks
+// GROUPING, which is recorded as an error.
+func (qb *QueryBuilder) SelectGrouping(column, alias string) *QueryBuilder {
+	if qb.dialect() == SQLite {
+		qb.addErr("GROUPING() is not supported by SQLite")
+	}
+	return qb.SelectRaw("GROUPING(" + column + ") AS " + alias)
+}
+
+// writeGroupBy writes the GROUP BY clause, if any.
+func (qb *QueryBuilder) writeGroupBy(query *strings.Builder) {
+	groupBy := qb.groupByColumns()
+	if len(groupBy) == 0 {
+		return
+	}
+	query.WriteString(" GROUP BY ")
+	switch {
+	case !qb.GroupRollup:
+		query.WriteString(strings.Join(groupBy, ", "))
+	case qb.dialect() == MySQL:
+		query.WriteString(strings.Join(groupBy, ", "))
+		query.WriteString(" WITH ROLLUP")
+	default:
+		query.WriteString("ROLLUP (")
+		query.WriteString(strings.Join(groupBy, ", "))
+		query.WriteString(")")
+	}
+}
+
+// GroupByPosition appends ordinal GROUP BY references ("GROUP BY 1, 2") to
+// the select list positions, 1-based. Ordinals are never quoted and may be
+// mixed with GroupBy column names. Positions < 1 are recorded as errors;
+// positions beyond the selected columns are reported by BuildErr.
+func (qb *QueryBuilder) GroupByPosition(positions ...int) *QueryBuilder {
+	for _, p := range positions {
+		if p < 1 {
+			qb.addErr("GROUP BY position %d must be >= 1", p)
+			continue
+		}
+		qb.GroupByColumns = append(qb.GroupByColumns, strconv.Itoa(p))
+	}
+	return qb
+}
+
+// isOrdinal reports whether a GROUP BY entry is a select-list position.
+func isOrdinal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// AutoGroupBy derives GROUP BY from every selected column that is not an
+// aggregate (COUNT(...), SUM(...), ...) when no GroupBy columns were given.
+// Aliases are stripped: Select("status AS s", "COUNT(*) AS n") groups by status.
+func (qb *QueryBuilder) AutoGroupBy() *QueryBuilder {
+	qb.AutoGroup = true
+	return qb
+}
+
+var (
+	aggregateRe = regexp.MustCompile(`(?i)\b(COUNT|SUM|AVG|MIN|MAX|ARRAY_AGG|STRING_AGG|GROUP_CONCAT|JSON_AGG|JSONB_AGG|BOOL_AND|BOOL_OR|GROUPING)\s*\(`)
+	aliasRe     = regexp.MustCompile(`(?i)\s+AS\s+\S+$`)
+)
+
+// groupByColumns returns the rendered GROUP BY entries: the explicit
+// GroupByColumns, or the derived list when AutoGroupBy is on.
+func (qb *QueryBuilder) groupByColumns() []string {
+	if len(qb.GroupByColumns) > 0 {
+		out := make([]string, len(qb.GroupByColumns))
+		for i, c := range qb.GroupByColumns {
+			if isOrdinal(c) {
+				out[i] = c
+			} else {
+				out[i] = qb.ident(c)
+			}
+		}
+		return out
+	}
+	if !qb.AutoGroup {
+		return nil
+	}
+
+	var out []string
+	for _, col := range qb.Columns {
+		if isWildcard(col) || aggregateRe.MatchString(col) {
+			continue
+		}
+		expr := aliasRe.ReplaceAllString(col, "")
+		if qb.RawColumns[col] {
+			out = append(out, expr)
+		} else {
+			out = append(out, qb.ident(expr))
+		}
+	}
+	return out
+}
+
 // Having adds a HAVING predicate (combined with AND by default).
 func (qb *QueryBuilder) Having(column string, op Operator, value interface{}) *QueryBuilder {
 	condition := Condition{
@@ -17,3 +138,18 @@ func (qb *QueryBuilder) Having(column string, op Operator, value interface{}) *Q
 	qb.HavingConditions = append(qb.HavingConditions, condition)
 	return qb
 }
+
+// HavingExpr adds a HAVING predicate on a raw aggregate expression (combined
+// with AND), e.g. HavingExpr("SUM(amount)", GT, 1000) renders
+// "SUM(amount) > $1". Unlike Having, expr is never quoted; value is bound
+// (NULL/NOTNULL ignore it).
+func (qb *QueryBuilder) HavingExpr(expr string, op Operator, value interface{}) *QueryBuilder {
+	raw := strings.ReplaceAll(expr, "?", "??") + " " + strings.ReplaceAll(string(op), "?", "??")
+	var args []interface{}
+	if op != NULL && op != NOTNULL {
+		raw += " ?"
+		args = []interface{}{value}
+	}
+	qb.HavingConditions = append(qb.HavingConditions, Condition{Raw: raw, Value: args, Logic: "AND"})
+	return qb
+}
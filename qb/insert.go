@@ -1,13 +1,17 @@
 package qb
 
 import (
+	"errors"
+	"fmt"
 	"sort"
 	"strings"
 )
 
-// Insert starts an INSERT statement for the given table and initializes InsertData.
+// Insert starts an INSERT statement for the given table and initializes InsertData,
+// discarding any unbuilt per-query state (builder configuration is kept).
 // Use Set/ Values to add column values. Supports RETURNING on dialects that allow it.
 func (qb *QueryBuilder) Insert(table string) *QueryBuilder {
+	qb.startStatement(INSERT)
 	qb.QueryType = INSERT
 	qb.Table = table
 	qb.InsertData = make(map[string]interface{})
@@ -21,7 +25,8 @@ func (qb *QueryBuilder) Values(data map[string]interface{}) *QueryBuilder {
 	return qb
 }
 
-// Set adds or replaces a single column/value pair for INSERT.
+// Set adds or replaces a single column/value pair for INSERT. A RawExpr value
+// (e.g. Default()) is inlined instead of bound.
 func (qb *QueryBuilder) Set(column string, value interface{}) *QueryBuilder {
 	if qb.InsertData == nil {
 		qb.InsertData = make(map[string]interface{})
@@ -30,76 +35,249 @@ func (qb *QueryBuilder) Set(column string, value interface{}) *QueryBuilder {
 	return qb
 }
 
+// ValuesBatch sets the rows of a multi-row INSERT:
+// INSERT INTO t (a, b) VALUES ($1, $2), ($3, $4). The column list is the
+// sorted union of all rows' keys; a row missing a column binds NULL for it,
+// and rows whose column sets differ are reported by BuildErr, unless
+// WithMissingValue says how to fill them. Oracle has no multi-row VALUES, so
+// more than one row is reported there.
+func (qb *QueryBuilder) ValuesBatch(rows []map[string]interface{}) *QueryBuilder {
+	qb.InsertRows = rows
+	return qb
+}
+
+// WithMissingValue sets how ValuesBatch fills a column missing from a row:
+// NullValue renders NULL, DefaultKeyword renders DEFAULT so the column
+// default applies, e.g. VALUES ($1, $2), ($3, DEFAULT). Either way rows with
+// differing columns are no longer reported; MissingReported restores the
+// default. Like the other With* options it survives Reset.
+func (qb *QueryBuilder) WithMissingValue(mode MissingValue) *QueryBuilder {
+	qb.MissingValues = mode
+	return qb
+}
+
+// InsertFromSelect inserts the rows of sub: INSERT INTO t (columns) SELECT ...
+// Without columns the column list is omitted, so sub must select every
+// column in table order. sub is rendered into qb's parameter stream at build
+// time and is not reset; Set/ Values/ ValuesBatch data is ignored.
+func (qb *QueryBuilder) InsertFromSelect(sub *QueryBuilder, columns ...string) *QueryBuilder {
+	qb.InsertQuery = sub
+	qb.InsertColumns = columns
+	return qb
+}
+
+// BuiltQuery is one rendered statement: SQL plus its bound args.
+type BuiltQuery struct {
+	SQL  string
+	Args []interface{}
+}
+
+// BuildBatches renders the INSERT started with Insert for rows split into
+// statements of at most chunkSize rows each (all rows in one statement when
+// chunkSize <= 0), so huge bulk inserts stay under driver parameter limits.
+// Every statement numbers its placeholders from $1 and carries the rest of
+// the insert (ON CONFLICT, RETURNING). Like Build, it resets qb afterwards.
+func (qb *QueryBuilder) BuildBatches(rows []map[string]interface{}, chunkSize int) []BuiltQuery {
+	defer qb.Reset()
+
+	if chunkSize <= 0 {
+		chunkSize = len(rows)
+	}
+	var out []BuiltQuery
+	for start := 0; start < len(rows); start += chunkSize {
+		end := min(start+chunkSize, len(rows))
+		c := qb.Clone()
+		c.QueryType = INSERT
+		c.InsertRows = rows[start:end]
+		sql, args := c.render()
+		out = append(out, BuiltQuery{SQL: sql, Args: args})
+	}
+	return out
+}
+
+// batchColumns returns the sorted union of the keys of rows.
+func batchColumns(rows []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for col := range row {
+			if !seen[col] {
+				seen[col] = true
+				columns = append(columns, col)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// batchMismatch returns the index of the first row whose column set differs
+// from the first row's, or -1.
+func batchMismatch(rows []map[string]interface{}) int {
+	for i := 1; i < len(rows); i++ {
+		if len(rows[i]) != len(rows[0]) {
+			return i
+		}
+		for col := range rows[i] {
+			if _, ok := rows[0][col]; !ok {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// writeBatchValues writes (cols) VALUES (...), (...) for InsertRows.
+func (qb *QueryBuilder) writeBatchValues(query *strings.Builder) {
+	columns := batchColumns(qb.InsertRows)
+
+	query.WriteString(" (")
+	query.WriteString(qb.identList(columns))
+	query.WriteString(") VALUES ")
+	for i, row := range qb.InsertRows {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for j, col := range columns {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			v, ok := row[col]
+			if !ok {
+				switch qb.MissingValues {
+				case NullValue:
+					v = RawExpr("NULL")
+				case DefaultKeyword:
+					v = Default()
+				}
+			}
+			qb.writeInsertValue(query, v)
+		}
+		query.WriteString(")")
+	}
+}
+
+// writeInsertValue inlines a RawExpr value and binds anything else.
+func (qb *QueryBuilder) writeInsertValue(query *strings.Builder, v interface{}) {
+	if raw, ok := v.(RawExpr); ok {
+		query.WriteString(string(raw))
+		return
+	}
+	qb.writePlaceholder(query)
+	qb.Parameters = append(qb.Parameters, v)
+}
+
 func (qb *QueryBuilder) buildInsert() (string, []interface{}) {
 	var query strings.Builder
 
 	query.WriteString("INSERT INTO ")
-	query.WriteString(qb.Table)
+	query.WriteString(qb.table(qb.Table))
 
-	if len(qb.InsertData) == 0 {
-		if qb.PhStyle == DollarN {
-			// Postgres / (SQLite 3.35+)
-			query.WriteString(" DEFAULT VALUES")
-			// ON CONFLICT (just PG/SQLite)
-			qb.renderOnConflict(&query)
-			// RETURNING (just PG/SQLite)
-			if len(qb.ReturningColumns) > 0 {
-				query.WriteString(" RETURNING ")
-				query.WriteString(strings.Join(qb.ReturningColumns, ", "))
-			}
-		} else {
-			// MySQL
-			query.WriteString(" () VALUES ()")
+	if qb.InsertQuery != nil {
+		if len(qb.InsertColumns) > 0 {
+			query.WriteString(" (")
+			query.WriteString(qb.identList(qb.InsertColumns))
+			query.WriteString(")")
 		}
+		query.WriteString(" ")
+		query.WriteString(qb.renderSub(qb.InsertQuery))
+		qb.renderOnConflict(&query)
+		qb.renderReturning(&query)
 		return query.String(), qb.Parameters
 	}
 
-	columns := make([]string, 0, len(qb.InsertData))
-	for col := range qb.InsertData {
-		columns = append(columns, col)
+	if len(qb.InsertRows) > 0 {
+		qb.writeBatchValues(&query)
+		qb.renderOnConflict(&query)
+		qb.renderReturning(&query)
+		return query.String(), qb.Parameters
 	}
-	sort.Strings(columns)
 
-	placeholders := make([]string, 0, len(columns))
-	for _, column := range columns {
-		placeholders = append(placeholders, qb.placeholder())
-		qb.Parameters = append(qb.Parameters, qb.InsertData[column])
+	if len(qb.InsertData) == 0 {
+		if qb.dialect() == MySQL {
+			query.WriteString(" () VALUES ()")
+		} else {
+			// Postgres / SQLite (RETURNING needs 3.35+)
+			query.WriteString(" DEFAULT VALUES")
+			qb.renderOnConflict(&query)
+			qb.renderReturning(&query)
+		}
+		return query.String(), qb.Parameters
 	}
 
+	columns := sortedKeys(qb.InsertData)
+
 	query.WriteString(" (")
-	query.WriteString(strings.Join(columns, ", "))
+	query.WriteString(qb.identList(columns))
 	query.WriteString(") VALUES (")
-	query.WriteString(strings.Join(placeholders, ", "))
+	for i, column := range columns {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		qb.writeInsertValue(&query, qb.InsertData[column])
+	}
 	query.WriteString(")")
 
-	// ON CONFLICT (just in case: DollarN ⇒ PG/SQLite)
+	// ON CONFLICT (PG/SQLite; ON DUPLICATE KEY for a MySQL MergeKey)
 	qb.renderOnConflict(&query)
 
 	// RETURNING (just PG/SQLite)
-	if qb.PhStyle == DollarN && len(qb.ReturningColumns) > 0 {
-		query.WriteString(" RETURNING ")
-		query.WriteString(strings.Join(qb.ReturningColumns, ", "))
-	}
+	qb.renderReturning(&query)
 
 	return query.String(), qb.Parameters
 }
 
+// conflictErr reports an ON CONFLICT clause, or an INSERT without values or
+// with several VALUES rows, that the effective dialect cannot render.
+func (qb *QueryBuilder) conflictErr() error {
+	d := qb.dialect()
+	switch {
+	case d == MySQL && !qb.ConflictMerge && qb.hasConflictClause():
+		return errors.New("qb: ON CONFLICT is not supported on MySQL (use MergeKey)")
+	case (d == Oracle || d == SQLServer) && qb.hasConflictClause():
+		return fmt.Errorf("qb: ON CONFLICT is not supported on %s", d)
+	case d == Oracle && qb.InsertQuery == nil && len(qb.InsertRows) == 0 && len(qb.InsertData) == 0:
+		return errors.New("qb: INSERT without values (DEFAULT VALUES) is not supported on Oracle")
+	case d == Oracle && qb.InsertQuery == nil && len(qb.InsertRows) > 1:
+		return errors.New("qb: multi-row VALUES (ValuesBatch) is not supported on Oracle")
+	}
+	return nil
+}
+
+// hasConflictClause reports whether any ON CONFLICT option was chained.
+func (qb *QueryBuilder) hasConflictClause() bool {
+	return len(qb.ConflictColumns) > 0 || qb.ConflictConstraint != "" || qb.ConflictAuto ||
+		qb.ConflictDoNothing || len(qb.ConflictUpdateSet) > 0 || len(qb.ConflictUpdateOnly) > 0
+}
+
 func (qb *QueryBuilder) renderOnConflict(query *strings.Builder) {
-	if qb.PhStyle != DollarN {
+	switch qb.dialect() {
+	case MySQL:
+		if qb.ConflictMerge {
+			qb.renderOnDuplicateKey(query)
+		}
 		return
+	case Oracle, SQLServer:
+		return // reported by BuildErr
+	}
+	target := qb.ConflictColumns
+	if len(target) == 0 && qb.ConflictAuto {
+		target = qb.PrimaryKey
 	}
-	if len(qb.ConflictColumns) == 0 && qb.ConflictConstraint == "" &&
-		!qb.ConflictDoNothing && len(qb.ConflictUpdateSet) == 0 {
+	updates := qb.conflictUpdates()
+	if len(target) == 0 && qb.ConflictConstraint == "" &&
+		!qb.ConflictDoNothing && len(updates) == 0 {
 		return
 	}
 
-	query.WriteString(" ON CONFLICT ")
+	query.WriteString(" ON CONFLICT")
 	if qb.ConflictConstraint != "" {
-		query.WriteString("ON CONSTRAINT ")
+		query.WriteString(" ON CONSTRAINT ")
 		query.WriteString(qb.ConflictConstraint)
-	} else if len(qb.ConflictColumns) > 0 {
-		query.WriteString("(")
-		query.WriteString(strings.Join(qb.ConflictColumns, ", "))
+	} else if len(target) > 0 {
+		query.WriteString(" (")
+		query.WriteString(qb.identList(target))
 		query.WriteString(")")
 	}
 
@@ -108,26 +286,38 @@ func (qb *QueryBuilder) renderOnConflict(query *strings.Builder) {
 		return
 	}
 
-	if len(qb.ConflictUpdateSet) > 0 {
+	if len(updates) > 0 {
 		query.WriteString(" DO UPDATE SET ")
+		qb.writeConflictSet(query, updates)
+	}
+}
 
-		keys := make([]string, 0, len(qb.ConflictUpdateSet))
-		for k := range qb.ConflictUpdateSet {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-
-		parts := make([]string, 0, len(keys))
-		for _, col := range keys {
-			val := qb.ConflictUpdateSet[col]
-			if raw, ok := val.(RawExpr); ok {
-				parts = append(parts, col+" = "+string(raw))
-			} else {
-				ph := qb.placeholder()
-				qb.Parameters = append(qb.Parameters, val)
-				parts = append(parts, col+" = "+ph)
-			}
+// renderOnDuplicateKey writes the MySQL form of a MergeKey upsert. The
+// inserted row is aliased as excluded so Excluded("col") resolves as it does
+// on PostgreSQL.
+func (qb *QueryBuilder) renderOnDuplicateKey(query *strings.Builder) {
+	if updates := qb.conflictUpdates(); len(updates) > 0 {
+		query.WriteString(" AS excluded ON DUPLICATE KEY UPDATE ")
+		qb.writeConflictSet(query, updates)
+	} else if qb.ConflictDoNothing && len(qb.ConflictColumns) > 0 {
+		col := qb.ident(qb.ConflictColumns[0])
+		query.WriteString(" ON DUPLICATE KEY UPDATE " + col + " = " + col)
+	}
+}
+
+// writeConflictSet writes the sorted col = value assignments of set,
+// inlining RawExpr values.
+func (qb *QueryBuilder) writeConflictSet(query *strings.Builder, set map[string]interface{}) {
+	parts := make([]string, 0, len(set))
+	for _, col := range sortedKeys(set) {
+		val := set[col]
+		if raw, ok := val.(RawExpr); ok {
+			parts = append(parts, qb.ident(col)+" = "+string(raw))
+		} else {
+			ph := qb.placeholder()
+			qb.Parameters = append(qb.Parameters, val)
+			parts = append(parts, qb.ident(col)+" = "+ph)
 		}
-		query.WriteString(strings.Join(parts, ", "))
 	}
+	query.WriteString(strings.Join(parts, ", "))
 }
@@ -32,3 +32,34 @@ func (qb *QueryBuilder) RightJoin(table, condition string) *QueryBuilder {
 	qb.Joins = append(qb.Joins, join)
 	return qb
 }
+
+// JoinIf is like Join but appends the INNER JOIN only when cond is true,
+// e.g. JoinIf(city != "", "addresses a", "a.user_id = u.id").
+func (qb *QueryBuilder) JoinIf(cond bool, table, condition string) *QueryBuilder {
+	if !cond {
+		return qb
+	}
+	return qb.Join(table, condition)
+}
+
+// LeftJoinIf is like LeftJoin but appends the LEFT JOIN only when cond is true.
+func (qb *QueryBuilder) LeftJoinIf(cond bool, table, condition string) *QueryBuilder {
+	if !cond {
+		return qb
+	}
+	return qb.LeftJoin(table, condition)
+}
+
+// CrossJoin appends a CROSS JOIN (no ON condition).
+func (qb *QueryBuilder) CrossJoin(table string) *QueryBuilder {
+	qb.Joins = append(qb.Joins, Join{Type: CROSS, Table: table})
+	return qb
+}
+
+// JoinRaw appends a join expression verbatim at its position in the join
+// list, as an escape hatch for e.g. "NATURAL JOIN t" or
+// "JOIN t USING (x)". It is neither prefixed nor quoted.
+func (qb *QueryBuilder) JoinRaw(rawJoin string) *QueryBuilder {
+	qb.Joins = append(qb.Joins, Join{Raw: rawJoin})
+	return qb
+}
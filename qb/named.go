@@ -0,0 +1,64 @@
+package qb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// NamedArg is a condition value bound under a name by BuildNamed; see Named.
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Named wraps value so BuildNamed binds it as :name. Referencing the same
+// name in several places yields a single map entry, e.g.
+// Where("a.tenant_id", EQ, Named("tenant", 7)).Where("b.tenant_id", EQ, Named("tenant", 7)).
+// Build and BuildErr bind Named values positionally by their Value.
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// BuildNamed is like BuildErr but renders named placeholders (:name) for
+// drivers and tools that take a map of arguments (e.g. sqlx.NamedExec).
+// Named values keep their name; every other value is bound as :p1, :p2, ...
+// by its position in the statement. A name used with two different values is
+// an error. Like Build, it always resets per-query state.
+func (qb *QueryBuilder) BuildNamed() (string, map[string]interface{}, error) {
+	defer qb.Reset()
+
+	if err := errors.Join(append(qb.Errs, qb.validate()...)...); err != nil {
+		return "", nil, err
+	}
+
+	// render with $N markers, keeping the dialect the placeholder style implies
+	style, dialect := qb.PhStyle, qb.Dialect
+	qb.Dialect = qb.dialect()
+	qb.PhStyle = DollarN
+	sql, args := qb.renderRaw(0)
+	qb.PhStyle, qb.Dialect = style, dialect
+
+	names := make([]string, len(args))
+	named := make(map[string]interface{}, len(args))
+	for i, a := range args {
+		name, value := "p"+strconv.Itoa(i+1), a
+		if n, ok := a.(NamedArg); ok {
+			name, value = n.Name, n.Value
+			if prev, seen := named[name]; seen && !reflect.DeepEqual(prev, value) {
+				return "", nil, fmt.Errorf("qb: named parameter %q bound to %v and %v", name, prev, value)
+			}
+		}
+		names[i] = name
+		named[name] = value
+	}
+
+	sql = rewriteDollar(sql, func(n int) (string, bool) {
+		if n < 1 || n > len(names) {
+			return "", false
+		}
+		return ":" + names[n-1], true
+	})
+	return sql, named, nil
+}
@@ -1,5 +1,7 @@
 package qb
 
+import "slices"
+
 // OnConflict sets the ON CONFLICT target columns (PostgreSQL/SQLite).
 // Example: OnConflict("id", "email")
 func (qb *QueryBuilder) OnConflict(columns ...string) *QueryBuilder {
@@ -8,6 +10,36 @@ func (qb *QueryBuilder) OnConflict(columns ...string) *QueryBuilder {
 	return qb
 }
 
+// MergeKey sets the upsert merge key once for every dialect. On PostgreSQL/
+// SQLite it is the ON CONFLICT (cols) target. MySQL takes the key implicitly
+// from the table's unique indexes (cols should match one) and renders
+// ... VALUES (...) AS excluded ON DUPLICATE KEY UPDATE ..., so Excluded and
+// OnConflictSetExcluded work unchanged (MySQL 8.0.19+); DO NOTHING becomes
+// the no-op assignment col = col on the first key column.
+// Example: MergeKey("email").OnConflictSetExcluded("name")
+func (qb *QueryBuilder) MergeKey(columns ...string) *QueryBuilder {
+	qb.OnConflict(columns...)
+	qb.ConflictMerge = true
+	return qb
+}
+
+// WithPrimaryKey registers the table's (possibly composite) primary key so
+// OnConflictAuto can infer the conflict target. Like the other With* options
+// it survives Reset, which suits a builder dedicated to one table.
+func (qb *QueryBuilder) WithPrimaryKey(columns ...string) *QueryBuilder {
+	qb.PrimaryKey = columns
+	return qb
+}
+
+// OnConflictAuto uses the key registered with WithPrimaryKey as the
+// ON CONFLICT target, resolved at build time. Explicit OnConflict columns or a
+// constraint take precedence; without any key BuildErr reports an error.
+// Example: WithPrimaryKey("tenant_id", "id")...OnConflictAuto().OnConflictSetExcluded("name")
+func (qb *QueryBuilder) OnConflictAuto() *QueryBuilder {
+	qb.ConflictAuto = true
+	return qb
+}
+
 // OnConflictConstraint sets ON CONSTRAINT <name> as the conflict target.
 func (qb *QueryBuilder) OnConflictConstraint(name string) *QueryBuilder {
 	qb.ConflictConstraint = name
@@ -30,6 +62,7 @@ func (qb *QueryBuilder) OnConflictSet(column string, value interface{}) *QueryBu
 	}
 	qb.ConflictDoNothing = false
 	qb.ConflictUpdateSet[column] = value
+	qb.ConflictUpdateOnly = slices.DeleteFunc(qb.ConflictUpdateOnly, func(c string) bool { return c == column })
 	return qb
 }
 
@@ -40,3 +73,76 @@ func (qb *QueryBuilder) OnConflictSetMap(m map[string]interface{}) *QueryBuilder
 	}
 	return qb
 }
+
+// OnConflictSetExcluded sets each column to its incoming value on conflict:
+// DO UPDATE SET col = excluded.col (rendered in sorted column order).
+// Example: OnConflictSetExcluded("name", "email", "updated_at")
+func (qb *QueryBuilder) OnConflictSetExcluded(columns ...string) *QueryBuilder {
+	for _, c := range columns {
+		qb.OnConflictSet(c, Excluded(c))
+	}
+	return qb
+}
+
+// OnConflictUpdate updates only the named insert columns on conflict, each to
+// its incoming value: DO UPDATE SET col = excluded.col. It is the allow-list
+// counterpart of UpsertReturning's "every other column". Columns are checked
+// against Values/ValuesBatch at build time, so it may be chained before them;
+// a column that is not inserted is skipped and reported by BuildErr.
+// Example: Values(row).OnConflict("id").OnConflictUpdate("name", "updated_at")
+func (qb *QueryBuilder) OnConflictUpdate(columns ...string) *QueryBuilder {
+	qb.ConflictDoNothing = false
+	for _, c := range columns {
+		delete(qb.ConflictUpdateSet, c)
+		if !slices.Contains(qb.ConflictUpdateOnly, c) {
+			qb.ConflictUpdateOnly = append(qb.ConflictUpdateOnly, c)
+		}
+	}
+	return qb
+}
+
+// conflictUpdates returns the DO UPDATE SET assignments: ConflictUpdateSet
+// plus each inserted OnConflictUpdate column set to its excluded value.
+func (qb *QueryBuilder) conflictUpdates() map[string]interface{} {
+	if len(qb.ConflictUpdateOnly) == 0 {
+		return qb.ConflictUpdateSet
+	}
+	set := cloneMap(qb.ConflictUpdateSet)
+	if set == nil {
+		set = make(map[string]interface{}, len(qb.ConflictUpdateOnly))
+	}
+	inserted := qb.InsertedColumns()
+	for _, c := range qb.ConflictUpdateOnly {
+		if slices.Contains(inserted, c) {
+			set[c] = Excluded(c)
+		}
+	}
+	return set
+}
+
+// UpsertReturning composes INSERT + ON CONFLICT (conflictCols) DO UPDATE SET
+// every other column to its excluded value + RETURNING in one call
+// (PostgreSQL/SQLite). When data has no columns besides the conflict target
+// it falls back to DO NOTHING, which returns no row on conflict. RETURNING
+// defaults to * when no columns are given. MySQL has neither RETURNING nor
+// ON CONFLICT, so there both are dropped and reported by BuildErr.
+func (qb *QueryBuilder) UpsertReturning(table string, data map[string]interface{}, conflictCols []string, returning ...string) *QueryBuilder {
+	qb.Insert(table).Values(data).OnConflict(conflictCols...)
+
+	target := make(map[string]bool, len(conflictCols))
+	for _, c := range conflictCols {
+		target[c] = true
+	}
+	var update []string
+	for col := range data {
+		if !target[col] {
+			update = append(update, col)
+		}
+	}
+	if len(update) == 0 {
+		qb.OnConflictDoNothing()
+	} else {
+		qb.OnConflictSetExcluded(update...)
+	}
+	return qb.Returning(returning...)
+}
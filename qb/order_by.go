@@ -1,5 +1,7 @@
 package qb
 
+import "strings"
+
 // OrderBy appends an ascending ORDER BY on the given column.
 func (qb *QueryBuilder) OrderBy(column string) *QueryBuilder {
 	order := OrderBy{
@@ -20,14 +22,136 @@ func (qb *QueryBuilder) OrderByDesc(column string) *QueryBuilder {
 	return qb
 }
 
+// OrderByMany appends several ORDER BY specs in one call, e.g.
+// OrderByMany(OrderBy{Column: "a"}, OrderBy{Column: "b", Desc: true}).
+func (qb *QueryBuilder) OrderByMany(specs ...OrderBy) *QueryBuilder {
+	qb.OrderByArr = append(qb.OrderByArr, specs...)
+	return qb
+}
+
+// OrderByCols appends an ascending ORDER BY for each column.
+func (qb *QueryBuilder) OrderByCols(cols ...string) *QueryBuilder {
+	for _, c := range cols {
+		qb.OrderBy(c)
+	}
+	return qb
+}
+
+// OrderByExpr appends ORDER BY on a raw expression such as an aggregate,
+// e.g. OrderByExpr("COUNT(*)", true) renders "COUNT(*) DESC". Unlike OrderBy,
+// expr is never quoted.
+func (qb *QueryBuilder) OrderByExpr(expr string, desc bool) *QueryBuilder {
+	qb.OrderByArr = append(qb.OrderByArr, OrderBy{Column: expr, Desc: desc, Raw: true})
+	return qb
+}
+
+// randomOrder is the ORDER BY entry added by OrderByRandom; writeOrderBy
+// renders it per dialect.
+const randomOrder = "RANDOM()"
+
+// OrderByRandom appends a random ordering: ORDER BY RANDOM() on
+// PostgreSQL/SQLite, ORDER BY RAND() on MySQL, ORDER BY DBMS_RANDOM.VALUE
+// on Oracle and ORDER BY NEWID() on SQL Server. With Limit it samples n rows,
+// at the cost of a full scan and sort of the matching rows.
+func (qb *QueryBuilder) OrderByRandom() *QueryBuilder {
+	qb.OrderByArr = append(qb.OrderByArr, OrderBy{Column: randomOrder, Raw: true})
+	return qb
+}
+
+// OrderByDynamic appends ORDER BY entries parsed from a client-supplied spec
+// such as "name,-created_at" (a leading "-" means DESC, "+" or nothing ASC).
+// A field may carry modifiers after colons: "name:asc:nullslast",
+// "created_at:desc:nullsfirst". Each field is looked up in allowed, which
+// maps API names to real columns; fields not in allowed (or with unknown
+// modifiers) are dropped and recorded as errors for BuildErr, so untrusted
+// input never reaches the SQL.
+func (qb *QueryBuilder) OrderByDynamic(spec string, allowed map[string]string) *QueryBuilder {
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		desc := false
+		switch {
+		case strings.HasPrefix(field, "-"):
+			desc, field = true, field[1:]
+		case strings.HasPrefix(field, "+"):
+			field = field[1:]
+		}
+		parts := strings.Split(field, ":")
+		name := parts[0]
+		if name == "" {
+			continue
+		}
+
+		column, ok := allowed[name]
+		if !ok {
+			qb.addErr("sort field %q is not allowed", name)
+			continue
+		}
+
+		order := OrderBy{Column: column, Desc: desc}
+		valid := true
+		for _, mod := range parts[1:] {
+			switch strings.ToLower(strings.TrimSpace(mod)) {
+			case "asc":
+				order.Desc = false
+			case "desc":
+				order.Desc = true
+			case "nullsfirst":
+				order.Nulls = NullsFirst
+			case "nullslast":
+				order.Nulls = NullsLast
+			default:
+				qb.addErr("unknown sort modifier %q for field %q", mod, name)
+				valid = false
+			}
+		}
+		if valid {
+			qb.OrderByArr = append(qb.OrderByArr, order)
+		}
+	}
+	return qb
+}
+
+// nullsSuffix renders NULLS FIRST/LAST where the dialect supports it
+// (MySQL and SQL Server have neither).
+func (qb *QueryBuilder) nullsSuffix(n NullsOrder) string {
+	if d := qb.dialect(); d == MySQL || d == SQLServer {
+		return ""
+	}
+	switch n {
+	case NullsFirst:
+		return " NULLS FIRST"
+	case NullsLast:
+		return " NULLS LAST"
+	default:
+		return ""
+	}
+}
+
 // Limit sets the LIMIT value (rendered inline, not as a parameter).
+// Limit(0) renders an explicit LIMIT 0; a negative value clears the limit.
 func (qb *QueryBuilder) Limit(limit int) *QueryBuilder {
 	qb.LimitInt = limit
+	qb.LimitSet = limit >= 0
+	qb.LimitAllSet = false
+	return qb
+}
+
+// LimitAll renders an explicit unbounded LIMIT ALL on PostgreSQL, e.g.
+// LimitAll().Offset(20) renders LIMIT ALL OFFSET 20. It replaces any Limit;
+// other dialects have no LIMIT ALL and render no LIMIT clause, except that
+// an Offset still gets the unbounded LIMIT MySQL and SQLite require.
+func (qb *QueryBuilder) LimitAll() *QueryBuilder {
+	qb.LimitInt, qb.LimitSet = 0, false
+	qb.LimitAllSet = true
 	return qb
 }
 
 // Offset sets the OFFSET value (rendered inline, not as a parameter).
+// Offset(0) renders an explicit OFFSET 0; a negative value clears the offset.
+// Without a Limit, MySQL renders LIMIT 18446744073709551615 and SQLite
+// LIMIT -1 before it, since neither accepts a bare OFFSET.
 func (qb *QueryBuilder) Offset(offset int) *QueryBuilder {
 	qb.OffsetInt = offset
+	qb.OffsetSet = offset >= 0
 	return qb
 }
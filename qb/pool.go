@@ -0,0 +1,62 @@
+package qb
+
+import "sync"
+
+// Pool recycles QueryBuilders for hot paths that build many queries, reducing
+// allocations compared to calling NewQB per query. The zero value is ready to
+// use and safe for concurrent use.
+type Pool struct {
+	p sync.Pool
+}
+
+// Get returns a builder in the same state as NewQB().
+func (pl *Pool) Get() *QueryBuilder {
+	if qb, ok := pl.p.Get().(*QueryBuilder); ok {
+		return qb
+	}
+	return NewQB()
+}
+
+// Put resets qb to the NewQB() state (dropping any configuration such as
+// dialect or quoting) and returns it to the pool. qb must not be used after
+// Put. Args returned by an earlier Build stay valid.
+func (pl *Pool) Put(qb *QueryBuilder) {
+	if qb == nil {
+		return
+	}
+	qb.recycle()
+	pl.p.Put(qb)
+}
+
+// recycle resets qb to the NewQB() state like *qb = defaultQB(), but keeps
+// the capacity of the slices and maps the builder allocates itself so the
+// next user appends into them. Containers that may alias caller data
+// (Select's columns, Values' map, ...) and Parameters, which earlier Build
+// args may share, are dropped instead.
+func (qb *QueryBuilder) recycle() {
+	conds, scopes, having := qb.Conditions, qb.ScopeConditions, qb.HavingConditions
+	joins, orders, groups := qb.Joins, qb.OrderByArr, qb.GroupByColumns
+	ctes, hints, setOps, errs := qb.CTEs, qb.Hints, qb.SetOps, qb.Errs
+	updates, conflictSet := qb.UpdateData, qb.ConflictUpdateSet
+	raw, colArgs := qb.RawColumns, qb.ColumnArgs
+
+	*qb = defaultQB()
+	qb.Conditions, qb.ScopeConditions = truncate(conds), truncate(scopes)
+	qb.HavingConditions, qb.Joins = truncate(having), truncate(joins)
+	qb.OrderByArr, qb.GroupByColumns = truncate(orders), truncate(groups)
+	qb.CTEs, qb.Hints = truncate(ctes), truncate(hints)
+	qb.SetOps, qb.Errs = truncate(setOps), truncate(errs)
+	clear(updates)
+	clear(conflictSet)
+	clear(raw)
+	clear(colArgs)
+	qb.UpdateData, qb.ConflictUpdateSet = updates, conflictSet
+	qb.RawColumns, qb.ColumnArgs = raw, colArgs
+}
+
+// truncate empties s, zeroing its elements so they can be collected, and
+// keeps its capacity.
+func truncate[T any](s []T) []T {
+	clear(s)
+	return s[:0]
+}
@@ -1,28 +1,33 @@
 package qb
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 )
 
 // NewQB creates a new QueryBuilder with default DollarN placeholder style.
 // All per-query state is zeroed; placeholder counter starts from 0.
+// Slices and maps are allocated lazily on first use.
 func NewQB() *QueryBuilder {
-	return &QueryBuilder{
-		Conditions:       []Condition{},
-		Joins:            []Join{},
-		GroupByColumns:   []string{},
-		HavingConditions: []Condition{},
-		OrderByArr:       []OrderBy{},
-		Parameters:       []interface{}{},
-		PhStyle:          DollarN, // Default
-		ParamIndex:       0,
-		GuardWrites:      true, // Default
+	qb := defaultQB()
+	return &qb
+}
+
+// defaultQB returns the zero per-query state with default configuration.
+func defaultQB() QueryBuilder {
+	return QueryBuilder{
+		PhStyle:     DollarN, // Default
+		ParamIndex:  0,
+		GuardWrites: true, // Default
 	}
 }
 
-// WithPlaceholders sets the placeholder style (DollarN or QuestionMark)
+// WithPlaceholders sets the placeholder style (DollarN, QuestionMark, ColonN,
+// AtPN)
 // and resets the internal placeholder counter. It returns qb for chaining.
 func (qb *QueryBuilder) WithPlaceholders(style PlaceholderStyle) *QueryBuilder {
 	qb.PhStyle = style
@@ -31,8 +36,10 @@ func (qb *QueryBuilder) WithPlaceholders(style PlaceholderStyle) *QueryBuilder {
 }
 
 // Returning adds a RETURNING clause for INSERT/ UPDATE/ DELETE.
-// If called with no columns, it defaults to RETURNING *.
-// Note: MySQL generally does not support RETURNING.
+// If called with no columns, it defaults to RETURNING *. Entries are rendered
+// verbatim, so expressions and aliases ("id AS new_id") are allowed.
+// Note: MySQL, Oracle and SQL Server do not support it; BuildErr reports it
+// there.
 func (qb *QueryBuilder) Returning(columns ...string) *QueryBuilder {
 	if len(columns) == 0 {
 		qb.ReturningColumns = []string{"*"}
@@ -42,47 +49,487 @@ func (qb *QueryBuilder) Returning(columns ...string) *QueryBuilder {
 	return qb
 }
 
+// ReturningAs appends "expr AS alias" to the RETURNING list.
+// Example: Returning("created_at").ReturningAs("id", "new_id")
+func (qb *QueryBuilder) ReturningAs(expr, alias string) *QueryBuilder {
+	qb.ReturningColumns = append(qb.ReturningColumns, expr+" AS "+alias)
+	return qb
+}
+
+// returningSupported reports whether the effective dialect has RETURNING.
+// Oracle's RETURNING ... INTO needs output binds and SQL Server's OUTPUT
+// sits mid-statement, so both count as none.
+func (qb *QueryBuilder) returningSupported() bool {
+	switch qb.dialect() {
+	case MySQL, Oracle, SQLServer:
+		return false
+	default:
+		return true
+	}
+}
+
+// renderReturning writes the RETURNING clause, if any.
+// It is dropped where the dialect has none (and reported by BuildErr).
+func (qb *QueryBuilder) renderReturning(query *strings.Builder) {
+	if len(qb.ReturningColumns) > 0 && qb.returningSupported() {
+		query.WriteString(" RETURNING ")
+		query.WriteString(strings.Join(qb.ReturningColumns, ", "))
+	}
+}
+
 // Build renders the SQL string and the ordered parameter slice.
 // It resets the placeholder counter, collects args, and (via defer) clears
 // per-query state after rendering. Special cases:
-//   - INSERT with no values: renders "DEFAULT VALUES" for DollarN (PG/SQLite),
-//     or "() VALUES ()" for QuestionMark (MySQL).
+//   - INSERT with no values: renders "DEFAULT VALUES" (PG/SQLite/SQL
+//     Server), or "() VALUES ()" (MySQL); Oracle has no such form, so
+//     BuildErr reports it.
+//   - RETURNING is dropped for MySQL, Oracle and SQL Server (and reported
+//     by BuildErr).
 //   - IN([]) renders "(1=0)" and NOT IN([]) renders "(1=1)".
 func (qb *QueryBuilder) Build() (string, []interface{}) {
-	qb.Parameters = []interface{}{}
-	qb.ParamIndex = 0 // reset placeholders
 	defer func() { qb.Reset() }()
 
+	query, args := qb.render()
+	qb.lastSQL, qb.lastArgs = query, args
+	return query, args
+}
+
+// BuildOffset is like Build but numbers DollarN placeholders after offset
+// placeholders already used by the surrounding statement, so BuildOffset(3)
+// starts at $4, for embedding the query in hand-written SQL. The offset
+// applies to this build only; QuestionMark output is unaffected.
+func (qb *QueryBuilder) BuildOffset(offset int) (string, []interface{}) {
+	defer func() { qb.Reset() }()
+
+	query, args := qb.renderFrom(offset)
+	qb.lastSQL, qb.lastArgs = query, args
+	return query, args
+}
+
+// Template returns the SQL (with placeholders) produced by the most recent
+// Build. Build resets per-query state, so Template and Params are the way to
+// look at a query after the fact, e.g. to log or hash the template and the
+// parameters separately. Both are empty before the first Build; Reset keeps
+// them, and the next Build replaces them.
+func (qb *QueryBuilder) Template() string {
+	return qb.lastSQL
+}
+
+// Params returns the parameter slice returned by the most recent Build (the
+// same slice, not a copy). See Template for the lifecycle.
+func (qb *QueryBuilder) Params() []interface{} {
+	return qb.lastArgs
+}
+
+// GetColumns returns a copy of the selected columns, for query-rewriting
+// middleware; changing the result does not affect qb.
+func (qb *QueryBuilder) GetColumns() []string {
+	return cloneSlice(qb.Columns)
+}
+
+// UpdatedColumns returns the columns assigned by the UPDATE, sorted as in
+// SET, e.g. for audit logging. Call it before Build, which resets qb.
+func (qb *QueryBuilder) UpdatedColumns() []string {
+	if vt := qb.UpdateValues; vt != nil {
+		cols := make([]string, 0, len(vt.Columns))
+		for _, c := range vt.Columns {
+			if c != qb.UpdateKey {
+				cols = append(cols, c)
+			}
+		}
+		return cols
+	}
+	return sortedKeys(qb.UpdateData)
+}
+
+// InsertedColumns returns the columns written by the INSERT (the batch
+// union for ValuesBatch), sorted as in the column list. Call it before
+// Build, which resets qb.
+func (qb *QueryBuilder) InsertedColumns() []string {
+	if len(qb.InsertRows) > 0 {
+		return batchColumns(qb.InsertRows)
+	}
+	return sortedKeys(qb.InsertData)
+}
+
+// sortedKeys returns the keys of m in sorted order, which is the order
+// they render in.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// GetConditions returns a deep copy of the WHERE conditions (groups
+// included); changing the result does not affect qb. Scope conditions are
+// not included. Pair with SetConditions to transform them.
+func (qb *QueryBuilder) GetConditions() []Condition {
+	return cloneConditions(qb.Conditions)
+}
+
+// SetConditions replaces the WHERE conditions with a deep copy of conds.
+func (qb *QueryBuilder) SetConditions(conds []Condition) *QueryBuilder {
+	qb.Conditions = cloneConditions(conds)
+	return qb
+}
+
+// cloneConditions deep-copies conds, including nested groups.
+func cloneConditions(conds []Condition) []Condition {
+	out := cloneSlice(conds)
+	for i := range out {
+		if out[i].Group != nil {
+			out[i].Group = cloneConditions(out[i].Group)
+		}
+		out[i].Tuple = cloneSlice(out[i].Tuple)
+	}
+	return out
+}
+
+// render renders SQL and args from the current state without resetting it.
+// Named values are bound positionally by their Value.
+func (qb *QueryBuilder) render() (string, []interface{}) {
+	return qb.renderFrom(0)
+}
+
+// renderFrom is render with DollarN numbering continuing after offset.
+func (qb *QueryBuilder) renderFrom(offset int) (string, []interface{}) {
+	sql, args := qb.renderRaw(offset)
+	for i, a := range args {
+		if n, ok := a.(NamedArg); ok {
+			args[i] = n.Value
+		}
+	}
+	return sql, args
+}
+
+// renderRaw is renderFrom without unwrapping Named values.
+func (qb *QueryBuilder) renderRaw(offset int) (string, []interface{}) {
+	qb.Parameters = []interface{}{}
+	qb.ParamIndex = offset // reset placeholders
+	sql, args := qb.renderStatement()
+	if prefix := qb.leadingComment(); prefix != "" && sql != "" {
+		sql = prefix + sql
+	}
+	return sql, args
+}
+
+// peek renders the statement like Build but leaves the builder untouched,
+// so it can still be built (or peeked at) afterwards.
+func (qb *QueryBuilder) peek() (string, []interface{}) {
+	params, index := qb.Parameters, qb.ParamIndex
+	sql, args := qb.render()
+	qb.Parameters, qb.ParamIndex = params, index
+	return sql, args
+}
+
+// renderStatement renders the statement, WITH clause included, into the
+// current parameter stream (qb.Parameters / qb.ParamIndex) as-is.
+func (qb *QueryBuilder) renderStatement() (string, []interface{}) {
+	with := qb.renderWith()
+
+	var sql string
 	switch qb.QueryType {
 	case SELECT:
-		return qb.buildSelect()
+		sql, _ = qb.buildSelect()
 	case INSERT:
-		return qb.buildInsert()
+		sql, _ = qb.buildInsert()
 	case UPDATE:
-		return qb.buildUpdate()
+		sql, _ = qb.buildUpdate()
 	case DELETE:
-		return qb.buildDelete()
+		sql, _ = qb.buildDelete()
 	default:
 		return "", nil
 	}
+	return with + sql, qb.Parameters
+}
+
+// BuildErr is like Build but also reports problems recorded while chaining
+// and validates the statement against the dialect (strict mode). When any
+// are found it returns an empty SQL string, nil args and the joined errors.
+// Like Build, it always resets per-query state.
+func (qb *QueryBuilder) BuildErr() (string, []interface{}, error) {
+	if err := errors.Join(append(qb.Errs, qb.validate()...)...); err != nil {
+		qb.Reset()
+		return "", nil, err
+	}
+	query, args := qb.Build()
+	return query, args, nil
+}
+
+// validate reports statement problems that Build would render regardless.
+func (qb *QueryBuilder) validate() []error {
+	var errs []error
+	if qb.QueryType == SELECT && len(qb.HavingConditions) > 0 &&
+		len(qb.groupByColumns()) == 0 && qb.dialect() == SQLite {
+		errs = append(errs, errors.New("qb: HAVING without GROUP BY is not supported by SQLite"))
+	}
+	if qb.QueryType == SELECT && qb.GroupRollup && qb.dialect() == SQLite {
+		errs = append(errs, errors.New("qb: GROUP BY ROLLUP is not supported by SQLite"))
+	}
+	if qb.QueryType != SELECT && len(qb.ReturningColumns) > 0 && !qb.returningSupported() {
+		errs = append(errs, fmt.Errorf("qb: RETURNING is not supported on %s", qb.dialect()))
+	}
+	if qb.QueryType == INSERT {
+		if err := qb.conflictErr(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if qb.QueryType == INSERT && qb.ConflictAuto && len(qb.ConflictColumns) == 0 &&
+		qb.ConflictConstraint == "" && len(qb.PrimaryKey) == 0 {
+		errs = append(errs, errors.New("qb: OnConflictAuto without a primary key (see WithPrimaryKey)"))
+	}
+	if qb.QueryType == INSERT && len(qb.ConflictUpdateOnly) > 0 {
+		inserted := qb.InsertedColumns()
+		for _, c := range qb.ConflictUpdateOnly {
+			if !slices.Contains(inserted, c) {
+				errs = append(errs, fmt.Errorf("qb: OnConflictUpdate column %q is not among the inserted columns", c))
+			}
+		}
+	}
+	if qb.QueryType == INSERT {
+		i := batchMismatch(qb.InsertRows)
+		switch {
+		case i >= 0 && qb.MissingValues == MissingReported:
+			errs = append(errs, fmt.Errorf("qb: ValuesBatch row %d has different columns than row 0", i))
+		case i >= 0 && qb.MissingValues == DefaultKeyword && qb.dialect() == SQLite:
+			errs = append(errs, errors.New("qb: DEFAULT inside VALUES is not supported by SQLite"))
+		}
+	}
+	if qb.QueryType == UPDATE && qb.UpdateValues != nil {
+		errs = append(errs, qb.updateValuesErrs()...)
+	} else if qb.QueryType == UPDATE && len(qb.UpdateData) == 0 {
+		errs = append(errs, errors.New("qb: UPDATE has no SET assignments"))
+	}
+	if qb.QueryType == SELECT && qb.dialect() == SQLServer && (qb.OffsetSet || qb.LimitSet) &&
+		!qb.useTop() && len(qb.OrderByArr) == 0 {
+		errs = append(errs, errors.New("qb: OFFSET/ FETCH on SQL Server requires ORDER BY"))
+	}
+	if qb.QueryType == SELECT && qb.FromValuesTable != nil {
+		if err := qb.valuesTableErr(qb.FromValuesTable); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if qb.QueryType == SELECT && qb.LockStrength != "" && !qb.lockSupported() {
+		errs = append(errs, fmt.Errorf("qb: FOR %s is not supported by this dialect", qb.LockStrength))
+	}
+	if qb.QueryType == SELECT && len(qb.DistinctOnColumns) > 0 {
+		if err := qb.distinctOnErr(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, qb.cteErrs()...)
+	if qb.QueryType == SELECT {
+		errs = append(errs, qb.setOpErrs()...)
+	}
+	if qb.FromSub != nil {
+		errs = append(errs, qb.FromSub.Errs...)
+		errs = append(errs, qb.FromSub.validate()...)
+	}
+	if qb.InsertQuery != nil {
+		errs = append(errs, qb.InsertQuery.Errs...)
+		errs = append(errs, qb.InsertQuery.validate()...)
+	}
+	check := func(c Condition) {
+		if sub, ok := c.Value.(*QueryBuilder); ok {
+			errs = append(errs, sub.Errs...)
+			errs = append(errs, sub.validate()...)
+			if len(c.Tuple) > 0 && !sub.selectsWildcard() && len(sub.Columns) != len(c.Tuple) {
+				errs = append(errs, fmt.Errorf("qb: tuple IN compares %d columns with a subquery selecting %d", len(c.Tuple), len(sub.Columns)))
+			}
+			return
+		}
+		if (c.Op == IN || c.Op == NIN) && c.Raw == "" && c.Group == nil {
+			name := "WhereIn"
+			if c.Op == NIN {
+				name = "WhereNotIn"
+			}
+			values, ok := sliceToInterfaces(c.Value)
+			if !ok {
+				errs = append(errs, fmt.Errorf("qb: %s requires a slice/array, got %T", name, c.Value))
+			} else if slices.Contains(values, nil) {
+				// col IN (NULL) never matches; col NOT IN (..., NULL) matches no row at all
+				errs = append(errs, fmt.Errorf("qb: %s list for %s contains NULL, which never compares equal; add an IS NULL condition instead", name, c.Column))
+			}
+		}
+	}
+	walkConditions(qb.Conditions, check)
+	walkConditions(qb.ScopeConditions, check)
+	if !parensBalanced(qb.Conditions) {
+		errs = append(errs, errors.New("qb: unbalanced WhereOpenParen/ WhereCloseParen"))
+	}
+	if qb.QueryType == SELECT && !qb.selectsWildcard() {
+		for _, c := range qb.GroupByColumns {
+			if n, _ := strconv.Atoi(c); isOrdinal(c) && n > len(qb.Columns) {
+				errs = append(errs, fmt.Errorf("qb: GROUP BY position %d exceeds the %d selected columns", n, len(qb.Columns)))
+			}
+		}
+	}
+	return errs
+}
+
+// selectsWildcard reports whether the select list contains * or tbl.*, in
+// which case the number of output columns is unknown.
+func (qb *QueryBuilder) selectsWildcard() bool {
+	for _, c := range qb.Columns {
+		if isWildcard(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// addErr records a chaining problem for BuildErr.
+func (qb *QueryBuilder) addErr(format string, a ...interface{}) {
+	qb.Errs = append(qb.Errs, fmt.Errorf("qb: "+format, a...))
 }
 
 // Paginate is a convenience for LIMIT/OFFSET with 1-based page numbering.
-// Paginate(page, perPage) == LIMIT perPage OFFSET (page-1)*perPage.
+// Paginate(page, perPage) == LIMIT perPage OFFSET (page-1)*perPage; the first
+// page renders no OFFSET.
 func (qb *QueryBuilder) Paginate(page, perPage int) *QueryBuilder {
-	return qb.Limit(perPage).Offset((page - 1) * perPage)
+	qb.Limit(perPage)
+	if offset := (page - 1) * perPage; offset > 0 {
+		qb.Offset(offset)
+	}
+	return qb
+}
+
+// Clone returns a deep copy of the builder's configuration and per-query
+// state, so the copy can be modified and built independently of qb.
+// Condition values themselves are shared, not copied.
+func (qb *QueryBuilder) Clone() *QueryBuilder {
+	c := *qb
+	c.Columns = cloneSlice(qb.Columns)
+	c.CTEs = cloneSlice(qb.CTEs)
+	c.Hints = cloneSlice(qb.Hints)
+	c.DistinctOnColumns = cloneSlice(qb.DistinctOnColumns)
+	c.FromRawArgs = cloneSlice(qb.FromRawArgs)
+	c.Conditions = cloneConditions(qb.Conditions)
+	c.ScopeConditions = cloneConditions(qb.ScopeConditions)
+	c.Joins = cloneSlice(qb.Joins)
+	c.GroupByColumns = cloneSlice(qb.GroupByColumns)
+	c.HavingConditions = cloneConditions(qb.HavingConditions)
+	c.OrderByArr = cloneSlice(qb.OrderByArr)
+	c.LockOf = cloneSlice(qb.LockOf)
+	c.SetOps = cloneSlice(qb.SetOps)
+	c.ReturningColumns = cloneSlice(qb.ReturningColumns)
+	c.ConflictColumns = cloneSlice(qb.ConflictColumns)
+	c.PrimaryKey = cloneSlice(qb.PrimaryKey)
+	c.InsertData = cloneMap(qb.InsertData)
+	c.InsertRows = cloneSlice(qb.InsertRows)
+	c.InsertColumns = cloneSlice(qb.InsertColumns)
+	c.UpdateData = cloneMap(qb.UpdateData)
+	c.ConflictUpdateSet = cloneMap(qb.ConflictUpdateSet)
+	c.ConflictUpdateOnly = cloneSlice(qb.ConflictUpdateOnly)
+	c.RawColumns = cloneMap(qb.RawColumns)
+	c.ColumnArgs = cloneMap(qb.ColumnArgs)
+	c.Comment = cloneMap(qb.Comment)
+	c.Errs = cloneSlice(qb.Errs)
+	if qb.FromValuesTable != nil {
+		vt := *qb.FromValuesTable
+		c.FromValuesTable = &vt
+	}
+	if qb.UpdateValues != nil {
+		vt := *qb.UpdateValues
+		c.UpdateValues = &vt
+	}
+	c.Parameters = []interface{}{}
+	c.ParamIndex = 0
+	return &c
+}
+
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
+
+func cloneMap[V any](m map[string]V) map[string]V {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
 }
 
 // Reset clears the builder's per-query state in place while preserving
+// builder-level configuration (placeholder style, dialect, identifier
+// quoting, table prefix, comment tags, statement timeout, missing-value
+// mode, primary key and DisableGuard). After Configure it returns to the
+// configured baseline instead, with the builder-level configuration above
+// taken from qb as before.
 func (qb *QueryBuilder) Reset() *QueryBuilder {
-	style := qb.PhStyle
-
-	newQB := QueryBuilder{PhStyle: style, GuardWrites: true}
+	var newQB QueryBuilder
+	if qb.base != nil {
+		newQB = *qb.base.Clone()
+		newQB.Parameters = nil
+		newQB.base = qb.base
+	}
+	newQB.PhStyle = qb.PhStyle
+	newQB.Dialect = qb.Dialect
+	newQB.QuoteIdents, newQB.quoteSet = qb.QuoteIdents, qb.quoteSet
+	newQB.TablePrefix = qb.TablePrefix
+	newQB.Comment = qb.Comment
+	newQB.PrimaryKey = qb.PrimaryKey
+	newQB.StatementTimeout = qb.StatementTimeout
+	newQB.MissingValues = qb.MissingValues
+	newQB.GuardWrites = !qb.GuardDisabled
+	newQB.GuardDisabled = qb.GuardDisabled
+	newQB.lastSQL, newQB.lastArgs = qb.lastSQL, qb.lastArgs
 	*qb = newQB
 
 	return qb
 }
 
+// Configure applies opts to qb and records its resulting state as the
+// baseline Reset returns to, so a service builder keeps its scopes (e.g.
+// tenant_id) and configuration across every Build/ Reset cycle:
+//
+//	base := NewQB().WithDialect(MySQL).Scope("tenant_id", EQ, 7).Configure()
+//
+// opts are plain functions, e.g. func(b *QueryBuilder) { b.DisableGuard() },
+// and may be omitted to record what was chained before. Calling Configure
+// again records a new baseline.
+func (qb *QueryBuilder) Configure(opts ...func(*QueryBuilder)) *QueryBuilder {
+	for _, opt := range opts {
+		opt(qb)
+	}
+	base := qb.Clone()
+	base.base = nil
+	base.lastSQL, base.lastArgs = "", nil
+	qb.base = base
+	return qb
+}
+
+// blank returns a copy of qb reset to its builder configuration only,
+// without the Configure baseline, for deriving wrapper statements.
+func (qb *QueryBuilder) blank() *QueryBuilder {
+	c := qb.Clone()
+	c.base = nil
+	return c.Reset()
+}
+
+// startStatement prepares qb for a new statement of type t: per-query state
+// is reset (keeping builder configuration) unless qb already holds a SELECT
+// being assembled and t is SELECT, since SELECT parts may be chained before
+// Select itself. Scope conditions and CTEs added beforehand are kept, so a
+// scope or With injected ahead of the entry point is never silently dropped.
+func (qb *QueryBuilder) startStatement(t QueryType) {
+	if t == SELECT && qb.QueryType == SELECT {
+		return
+	}
+	scopes, ctes := qb.ScopeConditions, qb.CTEs
+	qb.Reset()
+	qb.ScopeConditions, qb.CTEs = scopes, ctes
+}
+
 // Safe re-enables write guards for this query (default behavior).
 func (qb *QueryBuilder) Safe() *QueryBuilder {
 	qb.GuardWrites = true
@@ -96,22 +543,104 @@ func (qb *QueryBuilder) Unsafe() *QueryBuilder {
 	return qb
 }
 
+// DisableGuard turns write guards off for the builder's lifetime: unlike
+// Unsafe it survives Build and Reset, so every later UPDATE/ DELETE without
+// a WHERE clause really touches the whole table. Reserve it for trusted
+// maintenance tooling; Safe still re-enables the guard for a single query.
+func (qb *QueryBuilder) DisableGuard() *QueryBuilder {
+	qb.GuardDisabled = true
+	qb.GuardWrites = false
+	return qb
+}
+
 // Excluded returns a RawExpr like "excluded.<col>", handy for
 // ON CONFLICT DO UPDATE SET col = excluded.col (PostgreSQL/SQLite).
 func Excluded(col string) RawExpr { return RawExpr("excluded." + col) }
 
+// Default returns the DEFAULT keyword as a RawExpr, so Set("b", Default())
+// lets the column fall back to its default: VALUES ($1, DEFAULT). SQLite does
+// not accept DEFAULT inside VALUES; omit the column there instead.
+func Default() RawExpr { return RawExpr("DEFAULT") }
+
 func (qb *QueryBuilder) buildConditions(query *strings.Builder, conditions []Condition) {
 	for i, condition := range conditions {
-		if i > 0 {
+		// no separator right after "(" or before ")"
+		if i > 0 && conditions[i-1].Op != openParen && condition.Op != closeParen {
 			query.WriteString(" ")
 			query.WriteString(condition.Logic) // AND / OR
 			query.WriteString(" ")
 		}
 
+		if condition.Op == openParen || condition.Op == closeParen {
+			query.WriteString(string(condition.Op))
+			continue
+		}
+
+		if condition.Not && condition.Group == nil {
+			// NOT (col op $n): render the plain condition inside the negation
+			inner := condition
+			inner.Not = false
+			query.WriteString("NOT (")
+			qb.buildConditions(query, []Condition{inner})
+			query.WriteString(")")
+			continue
+		}
+
+		if condition.Group != nil {
+			if condition.Not {
+				query.WriteString("NOT ")
+			}
+			query.WriteString("(")
+			qb.buildConditions(query, condition.Group)
+			query.WriteString(")")
+			continue
+		}
+
+		if condition.Raw != "" {
+			args, _ := condition.Value.([]interface{})
+			qb.writeRaw(query, condition.Raw, args)
+			continue
+		}
+
+		if ref, ok := condition.Value.(ColumnRef); ok {
+			// col = other_col (no binding)
+			qb.writeComparison(query, qb.ident(condition.Column), condition.Op, func() {
+				query.WriteString(qb.ident(string(ref)))
+			})
+			continue
+		}
+
+		if vb, ok := condition.Value.(valueBetweenColumns); ok {
+			// $1 BETWEEN low_col AND high_col
+			qb.writeCastPlaceholder(query, condition.Cast)
+			qb.Parameters = append(qb.Parameters, vb.Value)
+			query.WriteString(" BETWEEN ")
+			query.WriteString(qb.ident(vb.Low))
+			query.WriteString(" AND ")
+			query.WriteString(qb.ident(vb.High))
+			continue
+		}
+
+		if sub, ok := condition.Value.(*QueryBuilder); ok {
+			// col IN (SELECT ...) / (a, b) IN (SELECT ...) / EXISTS (SELECT ...) / col > (SELECT ...)
+			lhs := ""
+			if len(condition.Tuple) > 0 {
+				lhs = "(" + qb.identList(condition.Tuple) + ")"
+			} else if condition.Column != "" {
+				lhs = qb.ident(condition.Column)
+			}
+			qb.writeComparison(query, lhs, condition.Op, func() {
+				query.WriteString("(")
+				query.WriteString(qb.renderSub(sub))
+				query.WriteString(")")
+			})
+			continue
+		}
+
 		switch condition.Op {
 		case NULL, NOTNULL:
 			// col IS NULL / col IS NOT NULL
-			query.WriteString(condition.Column)
+			query.WriteString(qb.conditionColumn(condition))
 			query.WriteString(" ")
 			query.WriteString(string(condition.Op))
 
@@ -126,45 +655,253 @@ func (qb *QueryBuilder) buildConditions(query *strings.Builder, conditions []Con
 				continue
 			}
 
-			query.WriteString(condition.Column)
+			query.WriteString(qb.conditionColumn(condition))
 			query.WriteString(" ")
 			query.WriteString(string(condition.Op))
 			query.WriteString(" (")
 
-			phs := make([]string, len(values))
+			// write placeholders straight into the builder; "$nnnn, " per value
+			query.Grow(len(values) * (7 + len(condition.Cast)))
+			qb.Parameters = slices.Grow(qb.Parameters, len(values))
 			for j, v := range values {
-				phs[j] = qb.placeholder()
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				qb.writeCastPlaceholder(query, condition.Cast)
 				qb.Parameters = append(qb.Parameters, v)
 			}
-			query.WriteString(strings.Join(phs, ", "))
 			query.WriteString(")")
 
 		default:
-			//   (=, !=, >, >=, <, <=, LIKE, NOT LIKE, ...)
-			query.WriteString(condition.Column)
-			query.WriteString(" ")
-			query.WriteString(string(condition.Op))
-			query.WriteString(" ")
-			query.WriteString(qb.placeholder())
+			//   (=, !=, >, >=, <, <=, LIKE, NOT LIKE, ?, ?|, ...)
+			qb.writeComparison(query, qb.conditionColumn(condition), condition.Op, func() {
+				if condition.Func == dateFunc && qb.dialect() == Oracle {
+					// TRUNC(col) is a DATE; compare it with one, not a string
+					query.WriteString("TO_DATE(")
+					qb.writePlaceholder(query)
+					query.WriteString(", 'YYYY-MM-DD')")
+					return
+				}
+				qb.writeCastPlaceholder(query, condition.Cast)
+			})
 			qb.Parameters = append(qb.Parameters, condition.Value)
 		}
 	}
 }
 
+// writeComparison writes "lhs op rhs" (just "op rhs" when lhs is empty),
+// with rhs writing the right-hand side, and translates the NULL-safe
+// operators where the dialect has no IS DISTINCT FROM: on MySQL a IS
+// DISTINCT FROM b becomes NOT (a <=> b), on Oracle DECODE(a, b, 0, 1) = 1
+// and on SQL Server (before 2022) NOT EXISTS (SELECT a INTERSECT SELECT b),
+// both DECODE and INTERSECT treating two NULLs as equal.
+func (qb *QueryBuilder) writeComparison(query *strings.Builder, lhs string, op Operator, rhs func()) {
+	nullSafe := op == DISTINCT || op == NOTDISTINCT
+	if nullSafe && lhs != "" && qb.dialect() == SQLServer {
+		if op == DISTINCT {
+			query.WriteString("NOT ")
+		}
+		query.WriteString("EXISTS (SELECT " + lhs + " INTERSECT SELECT ")
+		rhs()
+		query.WriteString(")")
+		return
+	}
+	if nullSafe && lhs != "" && qb.dialect() == Oracle {
+		query.WriteString("DECODE(" + lhs + ", ")
+		rhs()
+		if op == DISTINCT {
+			query.WriteString(", 0, 1) = 1")
+		} else {
+			query.WriteString(", 0, 1) = 0")
+		}
+		return
+	}
+
+	not := op == DISTINCT && qb.dialect() == MySQL
+	if not {
+		query.WriteString("NOT (")
+	}
+	if lhs != "" {
+		query.WriteString(lhs)
+		query.WriteString(" ")
+	}
+	query.WriteString(qb.operator(op))
+	query.WriteString(" ")
+	rhs()
+	if not {
+		query.WriteString(")")
+	}
+}
+
+// conditionColumn renders a condition's left-hand side: the column, wrapped
+// in its function when Func is set.
+func (qb *QueryBuilder) conditionColumn(c Condition) string {
+	if c.Func == "" {
+		return qb.ident(c.Column)
+	}
+	return qb.funcCall(c.Func, qb.ident(c.Column))
+}
+
+// funcCall renders name(arg), translating the built-in helper functions
+// (array length, date parts) for the effective dialect.
+func (qb *QueryBuilder) funcCall(name, arg string) string {
+	d := qb.dialect()
+	switch name {
+	case arrayLenFunc:
+		switch d {
+		case MySQL:
+			name = "JSON_LENGTH"
+		case SQLite:
+			name = "json_array_length"
+		case Oracle:
+			return "JSON_VALUE(" + arg + ", '$.size()' RETURNING NUMBER)"
+		case SQLServer:
+			return "(SELECT COUNT(*) FROM OPENJSON(" + arg + "))"
+		}
+	case yearFunc, monthFunc:
+		switch d {
+		case MySQL, SQLServer:
+			return strings.ToUpper(name) + "(" + arg + ")"
+		case SQLite:
+			return "CAST(strftime('" + sqliteDatePart[name] + "', " + arg + ") AS INTEGER)"
+		default:
+			return "EXTRACT(" + strings.ToUpper(name) + " FROM " + arg + ")"
+		}
+	case dateFunc:
+		switch d {
+		case MySQL:
+			name = "DATE"
+		case SQLServer:
+			return "CAST(" + arg + " AS DATE)"
+		case Oracle:
+			name = "TRUNC"
+		}
+	}
+	return name + "(" + arg + ")"
+}
+
+// writeRaw writes a raw predicate, replacing each '?' with the next
+// placeholder and binding args in order; "??" stays a literal '?' (doubled
+// again under QuestionMark, as operator does). Quoted sections are kept
+// verbatim (see Renumber). A *QueryBuilder arg is rendered in place as a
+// parenthesized subquery sharing the parameter stream.
+func (qb *QueryBuilder) writeRaw(query *strings.Builder, raw string, args []interface{}) {
+	next := 0
+	scanMarkers(raw, query.WriteString, func() {
+		if next < len(args) {
+			if sub, ok := args[next].(*QueryBuilder); ok {
+				query.WriteString("(" + qb.renderSub(sub) + ")")
+				next++
+				return
+			}
+		}
+		qb.writePlaceholder(query)
+		if next < len(args) {
+			qb.Parameters = append(qb.Parameters, args[next])
+		}
+		next++
+	}, func() {
+		query.WriteString(qb.operator("?"))
+	})
+}
+
+// countRawPlaceholders counts the '?' placeholders in raw, skipping "??"
+// and quoted sections.
+func countRawPlaceholders(raw string) int {
+	n := 0
+	scanMarkers(raw, func(string) (int, error) { return 0, nil }, func() { n++ }, func() {})
+	return n
+}
+
+// operator renders op, doubling any '?' under the QuestionMark style so
+// operators like jsonb "?" are not taken for placeholders, and translating
+// dialect-specific operators.
+func (qb *QueryBuilder) operator(op Operator) string {
+	if op == DISTINCT || op == NOTDISTINCT {
+		switch qb.dialect() {
+		case MySQL:
+			return "<=>" // DISTINCT is negated around the comparison, see writeComparison
+		case SQLite:
+			if op == DISTINCT {
+				return "IS NOT"
+			}
+			return "IS"
+		}
+	}
+	if qb.PhStyle == QuestionMark && strings.Contains(string(op), "?") {
+		return strings.ReplaceAll(string(op), "?", "??")
+	}
+	return string(op)
+}
+
+// castSuffix renders a PostgreSQL "::type" cast, or "" when no cast is set.
+func castSuffix(castType string) string {
+	if castType == "" {
+		return ""
+	}
+	return "::" + castType
+}
+
+// writeCastPlaceholder writes the next placeholder cast to castType, if set:
+// $1::uuid, or the standard CAST(:1 AS type) where "::" is not valid.
+func (qb *QueryBuilder) writeCastPlaceholder(query *strings.Builder, castType string) {
+	switch {
+	case castType == "":
+		qb.writePlaceholder(query)
+	case qb.dialect() == Oracle || qb.dialect() == SQLServer:
+		query.WriteString("CAST(")
+		qb.writePlaceholder(query)
+		query.WriteString(" AS " + castType + ")")
+	default:
+		qb.writePlaceholder(query)
+		query.WriteString(castSuffix(castType))
+	}
+}
+
 // placeholder returns the next placeholder according to the configured style.
 func (qb *QueryBuilder) placeholder() string {
-	switch qb.PhStyle {
-	case DollarN:
-		qb.ParamIndex++
-		return fmt.Sprintf("$%d", qb.ParamIndex)
-	default:
+	prefix := qb.PhStyle.numberPrefix()
+	if prefix == "" {
 		return "?"
 	}
+	qb.ParamIndex++
+	return prefix + strconv.Itoa(qb.ParamIndex)
+}
+
+// writePlaceholder writes the next placeholder directly into query,
+// avoiding the intermediate string placeholder allocates.
+func (qb *QueryBuilder) writePlaceholder(query *strings.Builder) {
+	prefix := qb.PhStyle.numberPrefix()
+	if prefix == "" {
+		query.WriteByte('?')
+		return
+	}
+	qb.ParamIndex++
+	var buf [20]byte
+	query.WriteString(prefix)
+	query.Write(strconv.AppendInt(buf[:0], int64(qb.ParamIndex), 10))
 }
 
 // sliceToInterfaces converts any slice/array (except []byte) to []interface{}.
-// Returns (nil, false) if the input is not a slice/array.
+// Nil pointer/interface elements become untyped nil, so every driver binds
+// them as NULL. Returns (nil, false) if the input is not a slice/array.
 func sliceToInterfaces(v interface{}) ([]interface{}, bool) {
+	if vs, ok := v.([]interface{}); ok {
+		// already the right shape; copy only to normalize typed nils, so the
+		// caller's slice is never modified
+		for i, e := range vs {
+			if e != nil && isNilValue(e) {
+				vs = slices.Clone(vs)
+				for j := i; j < len(vs); j++ {
+					if isNilValue(vs[j]) {
+						vs[j] = nil
+					}
+				}
+				break
+			}
+		}
+		return vs, true
+	}
 	val := reflect.ValueOf(v)
 	k := val.Kind()
 	if k != reflect.Slice && k != reflect.Array {
@@ -175,9 +912,24 @@ func sliceToInterfaces(v interface{}) ([]interface{}, bool) {
 		// If user really meant []byte inside IN, it's unusual — return single element
 		return []interface{}{v}, true
 	}
+	ek := val.Type().Elem().Kind()
+	nilable := ek == reflect.Pointer || ek == reflect.Interface
 	out := make([]interface{}, val.Len())
 	for i := 0; i < val.Len(); i++ {
-		out[i] = val.Index(i).Interface()
+		ev := val.Index(i)
+		if nilable && ev.IsNil() {
+			continue // leave untyped nil
+		}
+		out[i] = ev.Interface()
 	}
 	return out, true
 }
+
+// isNilValue reports whether v is nil or a typed nil pointer.
+func isNilValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
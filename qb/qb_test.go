@@ -1,9 +1,14 @@
 package qb
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSelectBasic(t *testing.T) {
@@ -727,3 +732,2992 @@ func TestBuild_UnknownQueryType_DefaultBranch(t *testing.T) {
 		t.Fatalf("builder should still work after default Build() + Reset()")
 	}
 }
+
+func TestLimitOffset_UnsetVsZero(t *testing.T) {
+	t.Run("unset", func(t *testing.T) {
+		sql, _ := NewQB().Select("id").From("t").Build()
+		if strings.Contains(sql, "LIMIT") || strings.Contains(sql, "OFFSET") {
+			t.Fatalf("expected no LIMIT/OFFSET, got: %s", sql)
+		}
+	})
+
+	t.Run("Limit(0)", func(t *testing.T) {
+		sql, _ := NewQB().Select("id").From("t").Limit(0).Offset(0).Build()
+		want := "SELECT id FROM t LIMIT 0 OFFSET 0"
+		if sql != want {
+			t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+		}
+	})
+
+	t.Run("Limit(10)", func(t *testing.T) {
+		sql, _ := NewQB().Select("id").From("t").Limit(10).Build()
+		want := "SELECT id FROM t LIMIT 10"
+		if sql != want {
+			t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+		}
+	})
+
+	t.Run("negative clears", func(t *testing.T) {
+		sql, _ := NewQB().Select("id").From("t").Limit(10).Limit(-1).Build()
+		if strings.Contains(sql, "LIMIT") {
+			t.Fatalf("expected LIMIT to be cleared, got: %s", sql)
+		}
+	})
+}
+
+func TestPaginateFirstPageNoOffset(t *testing.T) {
+	sql, _ := NewQB().Select("id").From("t").Paginate(1, 20).Build()
+	want := "SELECT id FROM t LIMIT 20"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestWhereStruct_OperatorsAndSkippedZero(t *testing.T) {
+	type filter struct {
+		MinAge *int   `qb:"age,gte"`
+		Name   string `qb:"name,like,omitempty"`
+		Status string `qb:"status,omitempty"`
+		Role   string `qb:"role,omitempty"`
+		Note   string
+	}
+	minAge := 18
+	sql, args := NewQB().
+		WithPlaceholders(DollarN).
+		Select("id").
+		From("users").
+		WhereStruct(filter{MinAge: &minAge, Name: "%ali%", Status: "active"}).
+		Build()
+
+	want := "SELECT id FROM users WHERE age >= $1 AND name LIKE $2 AND status = $3"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	wantArgs := []interface{}{18, "%ali%", "active"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("args mismatch:\n got: %#v\nwant: %#v", args, wantArgs)
+	}
+}
+
+func TestWhereStruct_InvalidInputReported(t *testing.T) {
+	type bad struct {
+		X int `qb:"x,between"`
+		Y int `qb:"y"`
+	}
+	q := NewQB().Select("id").From("t").WhereStruct(bad{X: 1, Y: 2})
+	if _, _, err := q.Clone().BuildErr(); err == nil || !strings.Contains(err.Error(), `unknown operator "between"`) {
+		t.Fatalf("expected unknown operator error, got: %v", err)
+	}
+	if sql, _ := q.Build(); sql != "SELECT id FROM t WHERE y = $1" {
+		t.Fatalf("invalid field should be skipped: %s", sql)
+	}
+
+	_, _, err := NewQB().Select("id").From("t").WhereStruct(42).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "expects a struct, got int") {
+		t.Fatalf("expected non-struct error, got: %v", err)
+	}
+}
+
+func TestWhereStruct_NilPointerIsNull(t *testing.T) {
+	type filter struct {
+		ManagerID *int `qb:"manager_id"`
+		TeamID    *int `qb:"team_id,neq"`
+		MinAge    *int `qb:"age,gte"`
+	}
+	sql, args := NewQB().Select("id").From("users").WhereStruct(filter{}).Build()
+	if want := "SELECT id FROM users WHERE manager_id IS NULL AND team_id IS NOT NULL"; sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 0 {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestWhereConditions_MixedLogic(t *testing.T) {
+	conds := []Condition{
+		{Column: "a", Op: EQ, Value: 1, Logic: "OR"}, // leading logic is ignored
+		{Column: "b", Op: EQ, Value: 2, Logic: "or"},
+		{Column: "c", Op: GT, Value: 3},
+	}
+	sql, args := NewQB().
+		WithPlaceholders(DollarN).
+		Select("*").
+		From("t").
+		WhereConditions(conds...).
+		Build()
+
+	want := "SELECT * FROM t WHERE a = $1 OR b = $2 AND c > $3"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	wantArgs := []interface{}{1, 2, 3}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("args mismatch:\n got: %#v\nwant: %#v", args, wantArgs)
+	}
+}
+
+func TestWhereConditions_AppendsAfterExisting(t *testing.T) {
+	sql, _ := NewQB().
+		WithPlaceholders(DollarN).
+		Select("*").
+		From("t").
+		Where("x", EQ, 1).
+		WhereConditions(Condition{Column: "y", Op: EQ, Value: 2, Logic: "bogus"}).
+		Build()
+
+	want := "SELECT * FROM t WHERE x = $1 AND y = $2"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestCountDistinct_PreservesWhereAndDropsOrderLimit(t *testing.T) {
+	base := NewQB().
+		WithPlaceholders(DollarN).
+		Select("o.id", "o.total").
+		From("orders o").
+		Join("users u", "u.id = o.user_id").
+		Where("o.status", EQ, "paid").
+		WhereIn("u.country", []string{"DE", "FR"}).
+		OrderByDesc("o.created_at").
+		Limit(20).
+		Offset(40)
+
+	sql, args := base.CountDistinct("user_id").Build()
+	want := "SELECT COUNT(DISTINCT user_id) FROM orders o INNER JOIN users u ON u.id = o.user_id " +
+		"WHERE o.status = $1 AND u.country IN ($2, $3)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	wantArgs := []interface{}{"paid", "DE", "FR"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("args mismatch:\n got: %#v\nwant: %#v", args, wantArgs)
+	}
+
+	// the original builder is untouched and still renders the page query
+	pageSQL, _ := base.Build()
+	if !strings.HasPrefix(pageSQL, "SELECT o.id, o.total") || !strings.HasSuffix(pageSQL, "LIMIT 20 OFFSET 40") {
+		t.Fatalf("base builder was modified: %s", pageSQL)
+	}
+}
+
+func TestCountQuery_WrapsGroupBy(t *testing.T) {
+	sql, _ := NewQB().
+		Select("status").
+		From("users").
+		GroupBy("status").
+		OrderBy("status").
+		CountQuery().
+		Build()
+
+	want := "SELECT COUNT(*) FROM (SELECT status FROM users GROUP BY status) AS qb_count"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestCountQuery_WithQuoting(t *testing.T) {
+	base := NewQB().WithQuoting(true).Select("id").From("users").Where("active", EQ, true)
+
+	sql, _ := base.CountQuery().Build()
+	if want := `SELECT COUNT(*) FROM "users" WHERE "active" = $1`; sql != want {
+		t.Fatalf("count sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	sql, _ = base.CountDistinct("users.email").Build()
+	if want := `SELECT COUNT(DISTINCT "users"."email") FROM "users" WHERE "active" = $1`; sql != want {
+		t.Fatalf("distinct sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	sql, _ = NewQB().WithQuoting(true).Select("status").From("users").GroupBy("status").CountQuery().Build()
+	if want := `SELECT COUNT(*) FROM (SELECT "status" FROM "users" GROUP BY "status") AS "qb_count"`; sql != want {
+		t.Fatalf("grouped sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestClone_Independent(t *testing.T) {
+	a := NewQB().Select("id").From("t").Where("x", EQ, 1)
+	b := a.Clone().Where("y", EQ, 2)
+
+	sqlA, _ := a.Build()
+	sqlB, _ := b.Build()
+	if strings.Contains(sqlA, "y =") {
+		t.Fatalf("clone leaked into original: %s", sqlA)
+	}
+	if !strings.Contains(sqlB, "x = $1 AND y = $2") {
+		t.Fatalf("unexpected clone sql: %s", sqlB)
+	}
+}
+
+func TestSelectRaw_WithQuoting(t *testing.T) {
+	sql, args := NewQB().
+		WithPlaceholders(DollarN).
+		WithQuoting(true).
+		Select("id", "u.name").
+		SelectRaw("EXTRACT(year FROM created_at) AS y").
+		From("users u").
+		Where("u.active", EQ, true).
+		Build()
+
+	want := `SELECT "id", "u"."name", EXTRACT(year FROM created_at) AS y FROM "users" "u" WHERE "u"."active" = $1`
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 1 || args[0] != true {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestQuoting_MySQLBackticksAndAlias(t *testing.T) {
+	sql, _ := NewQB().
+		WithPlaceholders(QuestionMark).
+		WithQuoting(true).
+		Select("name AS n").
+		From("users").
+		OrderByDesc("created_at").
+		Build()
+
+	want := "SELECT `name` AS `n` FROM `users` ORDER BY `created_at` DESC"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestQuoting_SurvivesResetAndOffByDefault(t *testing.T) {
+	b := NewQB().WithQuoting(true)
+	b.Select("id").From("t").Build()
+	sql, _ := b.Select("id").From("t").Build()
+	if sql != `SELECT "id" FROM "t"` {
+		t.Fatalf("expected quoting to survive Reset, got: %s", sql)
+	}
+
+	plain, _ := NewQB().Select("id").From("t").Build()
+	if plain != "SELECT id FROM t" {
+		t.Fatalf("quoting should be off by default, got: %s", plain)
+	}
+}
+
+func TestOrderByDynamic_ValidSpec(t *testing.T) {
+	allowed := map[string]string{"name": "u.name", "created_at": "u.created_at"}
+	sql, _, err := NewQB().
+		Select("id").
+		From("users u").
+		OrderByDynamic("name, -created_at", allowed).
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "SELECT id FROM users u ORDER BY u.name ASC, u.created_at DESC"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestOrderByDynamic_DisallowedField(t *testing.T) {
+	allowed := map[string]string{"name": "name"}
+
+	b := NewQB().Select("id").From("users").OrderByDynamic("name,-password; DROP TABLE users", allowed)
+	_, _, err := b.Clone().BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected disallowed-field error, got: %v", err)
+	}
+
+	// lenient Build drops the field instead
+	sql, _ := b.Build()
+	if sql != "SELECT id FROM users ORDER BY name ASC" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+}
+
+func TestReturningAs_InsertPostgres(t *testing.T) {
+	sql, _ := NewQB().
+		WithPlaceholders(DollarN).
+		Insert("users").
+		Set("name", "A").
+		ReturningAs("id", "new_id").
+		Returning("id AS new_id", "created_at").
+		Build()
+
+	want := "INSERT INTO users (name) VALUES ($1) RETURNING id AS new_id, created_at"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	sql2, _ := NewQB().
+		WithPlaceholders(DollarN).
+		Delete("users").
+		Where("id", EQ, 1).
+		Returning("created_at").
+		ReturningAs("id", "new_id").
+		Build()
+	if !strings.HasSuffix(sql2, " RETURNING created_at, id AS new_id") {
+		t.Fatalf("unexpected sql: %s", sql2)
+	}
+}
+
+func TestReturningAs_MySQLInsertOmitted(t *testing.T) {
+	sql, _ := NewQB().
+		WithPlaceholders(QuestionMark).
+		Insert("users").
+		Set("name", "A").
+		ReturningAs("id", "new_id").
+		Build()
+	if strings.Contains(sql, "RETURNING") {
+		t.Fatalf("did not expect RETURNING for MySQL, got: %s", sql)
+	}
+}
+
+func TestWhereJSONKeyOperators_Postgres(t *testing.T) {
+	keys := []string{"a", "b"}
+	sql, args := NewQB().
+		WithPlaceholders(DollarN).
+		Select("id").
+		From("docs").
+		WhereJSONHasKey("tags", "go").
+		WhereJSONHasAnyKey("attrs", keys).
+		Build()
+
+	want := "SELECT id FROM docs WHERE tags ? $1 AND attrs ?| $2"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	wantArgs := []interface{}{"go", keys}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("args mismatch:\n got: %#v\nwant: %#v", args, wantArgs)
+	}
+}
+
+func TestWhereJSONKeyOperators_QuestionMarkEscaped(t *testing.T) {
+	sql, args := NewQB().
+		WithPlaceholders(QuestionMark).
+		Select("id").
+		From("docs").
+		WhereJSONHasKey("tags", "go").
+		WhereJSONHasAnyKey("attrs", []string{"a"}).
+		Build()
+
+	want := "SELECT id FROM docs WHERE tags ?? ? AND attrs ??| ?"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 2 {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestRiskReport_UnsafeUpdate(t *testing.T) {
+	b := NewQB().Update("users").SetUpdate("role", "admin").Unsafe()
+	risks := b.RiskReport()
+	want := []string{"UPDATE without WHERE (guard disabled) affects every row"}
+	if !reflect.DeepEqual(risks, want) {
+		t.Fatalf("risks mismatch:\n got: %#v\nwant: %#v", risks, want)
+	}
+
+	// non-destructive: the builder still renders normally afterwards
+	if sql, _ := b.Build(); sql != "UPDATE users SET role = $1" {
+		t.Fatalf("unexpected sql after RiskReport: %s", sql)
+	}
+}
+
+func TestRiskReport_LeadingWildcardAndSelectStar(t *testing.T) {
+	risks := NewQB().
+		Select().
+		From("users").
+		WhereLike("name", "%ali").
+		WhereLike("email", "bob%").
+		RiskReport()
+
+	want := []string{
+		"SELECT * without LIMIT may scan a large table",
+		"LIKE with leading wildcard on name prevents index use",
+	}
+	if !reflect.DeepEqual(risks, want) {
+		t.Fatalf("risks mismatch:\n got: %#v\nwant: %#v", risks, want)
+	}
+}
+
+func TestRiskReport_Clean(t *testing.T) {
+	risks := NewQB().Delete("users").Where("id", EQ, 1).RiskReport()
+	if len(risks) != 0 {
+		t.Fatalf("expected no risks, got: %#v", risks)
+	}
+}
+
+func TestWhereCast_UUID(t *testing.T) {
+	id := "6f1c2a4e-0000-4000-8000-000000000001"
+	sql, args := NewQB().
+		WithPlaceholders(DollarN).
+		Select("*").
+		From("users").
+		WhereCast("id", EQ, id, "uuid").
+		WhereIn("org_id", []string{"a", "b"}).
+		Build()
+
+	want := "SELECT * FROM users WHERE id = $1::uuid AND org_id IN ($2, $3)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 3 || args[0] != id {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestHavingWithoutGroupBy_SQLiteStrict(t *testing.T) {
+	_, _, err := NewQB().
+		WithDialect(SQLite).
+		Select("COUNT(*)").
+		From("orders").
+		Having("COUNT(*)", GT, 1).
+		BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "HAVING without GROUP BY") {
+		t.Fatalf("expected HAVING-without-GROUP-BY error, got: %v", err)
+	}
+
+	// PostgreSQL accepts it (one implicit group)
+	sql, _, err := NewQB().
+		WithDialect(Postgres).
+		Select("COUNT(*)").
+		From("orders").
+		Having("COUNT(*)", GT, 1).
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error for Postgres: %v", err)
+	}
+	if sql != "SELECT COUNT(*) FROM orders HAVING COUNT(*) > $1" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+}
+
+func TestAutoGroupBy_NonAggregateColumns(t *testing.T) {
+	sql, args, err := NewQB().
+		WithDialect(SQLite).
+		Select("status", "country AS c", "COUNT(*) AS cnt", "SUM(total)").
+		From("orders").
+		Having("COUNT(*)", GT, 5).
+		AutoGroupBy().
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "SELECT status, country AS c, COUNT(*) AS cnt, SUM(total) FROM orders GROUP BY status, country HAVING COUNT(*) > ?"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 1 || args[0] != 5 {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestAutoGroupBy_ExplicitGroupByWins(t *testing.T) {
+	sql, _ := NewQB().
+		Select("status", "country", "COUNT(*)").
+		From("orders").
+		GroupBy("status").
+		AutoGroupBy().
+		Build()
+	if !strings.HasSuffix(sql, "GROUP BY status") {
+		t.Fatalf("expected explicit GROUP BY, got: %s", sql)
+	}
+}
+
+func TestWithDialect_SurvivesReset(t *testing.T) {
+	b := NewQB().WithDialect(SQLite)
+	b.Select("id").From("t").Build()
+	sql, _, err := b.Select("COUNT(*)").From("t").Having("COUNT(*)", GT, 1).BuildErr()
+	if err == nil {
+		t.Fatalf("expected SQLite dialect to survive Reset, got sql: %s", sql)
+	}
+}
+
+func TestFromValues_TwoRowsTwoColumns(t *testing.T) {
+	sql, args := NewQB().
+		WithPlaceholders(DollarN).
+		Select("t.id", "t.name").
+		FromValues("t", []string{"id", "name"}, [][]interface{}{{1, "a"}, {2, "b"}}).
+		Where("t.id", GT, 0).
+		Build()
+
+	want := "SELECT t.id, t.name FROM (VALUES ($1, $2), ($3, $4)) AS t(id, name) WHERE t.id > $5"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	wantArgs := []interface{}{1, "a", 2, "b", 0}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("args mismatch:\n got: %#v\nwant: %#v", args, wantArgs)
+	}
+}
+
+func TestFromValues_MySQLRowSyntax(t *testing.T) {
+	sql, _ := NewQB().
+		WithDialect(MySQL).
+		Select("*").
+		FromValues("v", []string{"id"}, [][]interface{}{{1}, {2}}).
+		Build()
+
+	want := "SELECT * FROM (VALUES ROW(?), ROW(?)) AS v(id)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestFromValues_SQLiteColumnListReported(t *testing.T) {
+	_, _, err := NewQB().WithDialect(SQLite).
+		Select("*").
+		FromValues("v", []string{"id"}, [][]interface{}{{1}, {2}}).
+		BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "not supported by SQLite") {
+		t.Fatalf("expected SQLite error, got: %v", err)
+	}
+
+	sql, _, err := NewQB().WithDialect(SQLite).
+		Select("*").
+		FromValues("v", nil, [][]interface{}{{1}, {2}}).
+		BuildErr()
+	if err != nil || sql != "SELECT * FROM (VALUES (?), (?)) AS v" {
+		t.Fatalf("unexpected result: %s, %v", sql, err)
+	}
+}
+
+func TestOrderByDynamic_NullsLast(t *testing.T) {
+	allowed := map[string]string{"name": "name", "created": "created_at"}
+	sql, _, err := NewQB().
+		WithDialect(Postgres).
+		Select("id").
+		From("users").
+		OrderByDynamic("name:asc:nullslast,created:desc", allowed).
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "SELECT id FROM users ORDER BY name ASC NULLS LAST, created_at DESC"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	// MySQL has no NULLS FIRST/LAST: the modifier is parsed but not rendered
+	mysql, _ := NewQB().
+		WithDialect(MySQL).
+		Select("id").
+		From("users").
+		OrderByDynamic("-name:nullsfirst", allowed).
+		Build()
+	if mysql != "SELECT id FROM users ORDER BY name DESC" {
+		t.Fatalf("unexpected MySQL sql: %s", mysql)
+	}
+}
+
+func TestOrderByDynamic_UnknownModifier(t *testing.T) {
+	_, _, err := NewQB().
+		Select("id").
+		From("users").
+		OrderByDynamic("name:sideways", map[string]string{"name": "name"}).
+		BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "unknown sort modifier") {
+		t.Fatalf("expected unknown-modifier error, got: %v", err)
+	}
+}
+
+func TestTablePrefix_SelectWithJoins(t *testing.T) {
+	sql, _ := NewQB().
+		WithTablePrefix("t123_").
+		Select("u.id", "o.total").
+		From("users u").
+		Join("orders o", "o.user_id = u.id").
+		LeftJoin("public.addresses a", "a.user_id = u.id").
+		Build()
+
+	want := "SELECT u.id, o.total FROM t123_users u INNER JOIN t123_orders o ON o.user_id = u.id " +
+		"LEFT JOIN public.t123_addresses a ON a.user_id = u.id"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestTablePrefix_WritesAndQuoting(t *testing.T) {
+	b := NewQB().WithTablePrefix("t1_").WithQuoting(true)
+
+	ins, _ := b.Insert("users").Set("name", "A").Build()
+	if ins != `INSERT INTO "t1_users" ("name") VALUES ($1)` {
+		t.Fatalf("unexpected insert: %s", ins)
+	}
+	upd, _ := b.Update("users").SetUpdate("name", "B").Where("id", EQ, 1).Build()
+	if upd != `UPDATE "t1_users" SET "name" = $1 WHERE "id" = $2` {
+		t.Fatalf("unexpected update: %s", upd)
+	}
+	del, _ := b.Delete("users").Where("id", EQ, 1).Build()
+	if del != `DELETE FROM "t1_users" WHERE "id" = $1` {
+		t.Fatalf("unexpected delete: %s", del)
+	}
+}
+
+func TestOnConflictSetExcluded_Sorted(t *testing.T) {
+	sql, args := NewQB().
+		WithPlaceholders(DollarN).
+		Insert("users").
+		Values(map[string]any{"id": 1, "name": "A", "email": "a@x", "updated_at": "now"}).
+		OnConflict("id").
+		OnConflictSetExcluded("updated_at", "name", "email").
+		Build()
+
+	wantFrag := "ON CONFLICT (id) DO UPDATE SET email = excluded.email, name = excluded.name, updated_at = excluded.updated_at"
+	if !strings.HasSuffix(sql, wantFrag) {
+		t.Fatalf("expected %q in sql, got: %s", wantFrag, sql)
+	}
+	if len(args) != 4 {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestWhereNullSafeEq_PerDialect(t *testing.T) {
+	build := func(d Dialect) (string, []interface{}) {
+		return NewQB().
+			WithDialect(d).
+			Select("id").
+			From("t").
+			WhereNullSafeEq("email", nil).
+			Build()
+	}
+
+	cases := []struct {
+		d    Dialect
+		want string
+	}{
+		{Postgres, "SELECT id FROM t WHERE email IS NOT DISTINCT FROM $1"},
+		{MySQL, "SELECT id FROM t WHERE email <=> ?"},
+		{SQLite, "SELECT id FROM t WHERE email IS ?"},
+	}
+	for _, c := range cases {
+		sql, args := build(c.d)
+		if sql != c.want {
+			t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, c.want)
+		}
+		if len(args) != 1 || args[0] != nil {
+			t.Fatalf("args mismatch: %#v", args)
+		}
+	}
+}
+
+func TestPool_GetReturnsFreshBuilder(t *testing.T) {
+	var pool Pool
+
+	b := pool.Get()
+	b.WithDialect(MySQL).WithQuoting(true).Select("id").From("t").Where("x", EQ, 1)
+	pool.Put(b)
+
+	b2 := pool.Get()
+	sql, args := b2.Select("id").From("t").Where("x", EQ, 1).Build()
+	if sql != "SELECT id FROM t WHERE x = $1" {
+		t.Fatalf("expected default configuration from pool, got: %s", sql)
+	}
+	if len(args) != 1 || args[0] != 1 {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+	pool.Put(b2)
+	pool.Put(nil) // no-op
+}
+
+func TestPool_PutKeepsCapacity(t *testing.T) {
+	// Put recycles through recycle; calling it directly keeps the check
+	// independent of sync.Pool dropping items (e.g. under -race).
+	b := NewQB().WithDialect(MySQL).Select("id").From("t").Where("a", EQ, 1).Where("b", EQ, 2).OrderBy("id")
+	b.recycle()
+
+	// appending into recycled slices does not allocate
+	allocs := testing.AllocsPerRun(100, func() {
+		b.Where("a", EQ, 1).Where("b", EQ, 2).OrderBy("id")
+		b.recycle()
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+
+	sql, _ := b.Select("id").From("t").Where("x", EQ, 1).Build()
+	if sql != "SELECT id FROM t WHERE x = $1" {
+		t.Fatalf("recycled builder not reset: %s", sql)
+	}
+}
+
+// benchSink forces builders to escape to the heap, as they do in services
+// that pass them across function boundaries.
+var benchSink *QueryBuilder
+
+func buildBenchQuery(b *QueryBuilder) {
+	benchSink = b
+	b.Select("id", "name").
+		From("users").
+		Where("age", GTE, 18).
+		Where("status", EQ, "active").
+		OrderBy("created_at").
+		Limit(10).
+		Build()
+}
+
+func BenchmarkNewQBPerCall(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buildBenchQuery(NewQB())
+	}
+}
+
+func BenchmarkPoolGetPut(b *testing.B) {
+	var pool Pool
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		qb := pool.Get()
+		buildBenchQuery(qb)
+		pool.Put(qb)
+	}
+}
+
+func BenchmarkWhereInLarge(b *testing.B) {
+	ids := make([]int, 1000)
+	for i := range ids {
+		ids[i] = i + 1000
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewQB().
+			Select("id").
+			From("users").
+			Where("active", EQ, true).
+			WhereIn("id", ids).
+			Build()
+	}
+}
+
+func TestWhereInLarge_PlaceholderSequence(t *testing.T) {
+	ids := make([]interface{}, 1000)
+	phs := make([]string, len(ids))
+	for i := range ids {
+		ids[i] = i
+		phs[i] = "$" + strconv.Itoa(i+2)
+	}
+	sql, args := NewQB().
+		Select("id").
+		From("users").
+		Where("active", EQ, true).
+		WhereIn("id", ids).
+		Build()
+
+	want := "SELECT id FROM users WHERE active = $1 AND id IN (" + strings.Join(phs, ", ") + ")"
+	if sql != want {
+		t.Fatalf("sql mismatch for large IN (len got %d, want %d)", len(sql), len(want))
+	}
+	if len(args) != 1001 || args[1000] != 999 {
+		t.Fatalf("args mismatch: len=%d", len(args))
+	}
+}
+
+func TestWhereLogic_AlternatingChain(t *testing.T) {
+	b := NewQB().WithPlaceholders(DollarN).Select("*").From("t")
+	for i, col := range []string{"a", "b", "c", "d"} {
+		logic := "AND"
+		if i%2 == 1 {
+			logic = "or"
+		}
+		b.WhereLogic(logic, col, EQ, i)
+	}
+	sql, args, err := b.BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "SELECT * FROM t WHERE a = $1 OR b = $2 AND c = $3 OR d = $4"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{0, 1, 2, 3}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestWhereLogic_InvalidDefaultsToAndWithError(t *testing.T) {
+	b := NewQB().Select("*").From("t").Where("a", EQ, 1).WhereLogic("XOR", "b", EQ, 2)
+	if _, _, err := b.Clone().BuildErr(); err == nil || !strings.Contains(err.Error(), "invalid WHERE logic") {
+		t.Fatalf("expected invalid-logic error, got: %v", err)
+	}
+	if sql, _ := b.Build(); sql != "SELECT * FROM t WHERE a = $1 AND b = $2" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+}
+
+func TestGroupByPosition_RendersOrdinals(t *testing.T) {
+	sql, _, err := NewQB().
+		WithQuoting(true).
+		Select("country").
+		SelectRaw("EXTRACT(year FROM created_at)", "COUNT(*)").
+		From("orders").
+		GroupByPosition(1, 2).
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `SELECT "country", EXTRACT(year FROM created_at), COUNT(*) FROM "orders" GROUP BY 1, 2`
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestGroupByPosition_MixedWithColumns(t *testing.T) {
+	sql, _ := NewQB().Select("a", "b", "COUNT(*)").From("t").GroupBy("a").GroupByPosition(2).Build()
+	if !strings.HasSuffix(sql, "GROUP BY a, 2") {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+}
+
+func TestGroupByPosition_OutOfRange(t *testing.T) {
+	_, _, err := NewQB().Select("a", "COUNT(*)").From("t").GroupByPosition(3).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "position 3 exceeds the 2 selected columns") {
+		t.Fatalf("expected out-of-range error, got: %v", err)
+	}
+	_, _, err = NewQB().Select("a").From("t").GroupByPosition(0).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "must be >= 1") {
+		t.Fatalf("expected position error, got: %v", err)
+	}
+}
+
+func TestAttachWhere_TwoConditionFragment(t *testing.T) {
+	frag := NewWhere().Where("a", EQ, 1).OrWhere("b", EQ, 2)
+
+	sql, args := NewQB().
+		WithPlaceholders(DollarN).
+		Select("*").
+		From("t").
+		Where("tenant_id", EQ, 9).
+		AttachWhere(frag).
+		Where("c", EQ, 3).
+		Build()
+
+	want := "SELECT * FROM t WHERE tenant_id = $1 AND (a = $2 OR b = $3) AND c = $4"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{9, 1, 2, 3}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	// the fragment is reusable; numbering follows the attaching query
+	sql2, _ := NewQB().Select("*").From("u").AttachWhere(frag).Build()
+	if sql2 != "SELECT * FROM u WHERE (a = $1 OR b = $2)" {
+		t.Fatalf("unexpected sql on reuse: %s", sql2)
+	}
+}
+
+func TestAttachWhere_SingleConditionNotWrapped(t *testing.T) {
+	sql, _ := NewQB().Select("*").From("t").Where("x", EQ, 1).
+		AttachWhere(NewWhere().OrWhere("y", EQ, 2)).
+		Build()
+	if sql != "SELECT * FROM t WHERE x = $1 AND y = $2" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+}
+
+func TestWhereGroup_AndOrGroups(t *testing.T) {
+	sql, args := NewQB().
+		Select("*").
+		From("t").
+		Where("active", EQ, true).
+		WhereGroup(func(g *QueryBuilder) {
+			g.Where("role", EQ, "admin").OrWhere("role", EQ, "owner")
+		}).
+		OrWhereGroup(func(g *QueryBuilder) {
+			g.WhereNull("deleted_at").WhereIn("id", []int{1, 2})
+		}).
+		WhereGroup(func(*QueryBuilder) {}). // empty: ignored
+		Build()
+
+	want := "SELECT * FROM t WHERE active = $1 AND (role = $2 OR role = $3) OR (deleted_at IS NULL AND id IN ($4, $5))"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 5 {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestRiskReport_LikeInsideGroup(t *testing.T) {
+	risks := NewQB().Select("id").From("t").Limit(1).
+		WhereGroup(func(g *QueryBuilder) { g.WhereLike("name", "%x").OrWhere("id", EQ, 1) }).
+		RiskReport()
+	if len(risks) != 1 || !strings.Contains(risks[0], "leading wildcard on name") {
+		t.Fatalf("unexpected risks: %#v", risks)
+	}
+}
+
+func TestSelectQualifiedWildcard(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		sql, _ := NewQB().Select("u.*", "p.title").From("users u").Join("posts p", "p.user_id = u.id").Build()
+		want := "SELECT u.*, p.title FROM users u INNER JOIN posts p ON p.user_id = u.id"
+		if sql != want {
+			t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+		}
+	})
+
+	t.Run("quoted", func(t *testing.T) {
+		sql, _ := NewQB().WithQuoting(true).Select("u.*", "p.title", "*").From("users u").Build()
+		want := `SELECT "u".*, "p"."title", * FROM "users" "u"`
+		if sql != want {
+			t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+		}
+	})
+
+	t.Run("mysql quoted", func(t *testing.T) {
+		sql, _ := NewQB().WithDialect(MySQL).WithQuoting(true).Select("u.*", "p.title").From("users u").Build()
+		want := "SELECT `u`.*, `p`.`title` FROM `users` `u`"
+		if sql != want {
+			t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+		}
+	})
+}
+
+func TestParamsAndTemplate_FromLastBuild(t *testing.T) {
+	b := NewQB()
+	if b.Template() != "" || b.Params() != nil {
+		t.Fatalf("expected empty Template/Params before Build")
+	}
+
+	sql, args := b.Select("id").From("users").Where("age", GT, 18).WhereIn("role", []string{"a", "b"}).Build()
+	if b.Template() != sql {
+		t.Fatalf("Template mismatch:\n got: %s\nwant: %s", b.Template(), sql)
+	}
+	params := b.Params()
+	if len(params) != len(args) || &params[0] != &args[0] {
+		t.Fatalf("Params should return the slice Build returned: %#v vs %#v", params, args)
+	}
+
+	// a new query does not disturb the cache until it is built
+	b.Select("name").From("t")
+	if b.Template() != sql {
+		t.Fatalf("Template changed before Build: %s", b.Template())
+	}
+	sql2, _ := b.Build()
+	if b.Template() != sql2 || len(b.Params()) != 0 {
+		t.Fatalf("cache not replaced by next Build: %s %#v", b.Template(), b.Params())
+	}
+}
+
+func TestScope_WrapsOredUserFilter(t *testing.T) {
+	sql, args := NewQB().
+		Select("*").
+		From("invoices").
+		Where("status", EQ, "open").
+		OrWhere("status", EQ, "overdue").
+		Scope("tenant_id", EQ, 42).
+		Build()
+
+	want := "SELECT * FROM invoices WHERE (status = $1 OR status = $2) AND tenant_id = $3"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"open", "overdue", 42}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestScope_SingleAndNoUserConditions(t *testing.T) {
+	one, _ := NewQB().Select("*").From("t").Where("a", EQ, 1).Scope("tenant_id", EQ, 7).Build()
+	if one != "SELECT * FROM t WHERE a = $1 AND tenant_id = $2" {
+		t.Fatalf("unexpected sql: %s", one)
+	}
+	none, _ := NewQB().Select("*").From("t").Scope("tenant_id", EQ, 7).Build()
+	if none != "SELECT * FROM t WHERE tenant_id = $1" {
+		t.Fatalf("unexpected sql: %s", none)
+	}
+}
+
+func TestScope_DoesNotSatisfyWriteGuard(t *testing.T) {
+	sql, _ := NewQB().Update("t").SetUpdate("x", 1).Scope("tenant_id", EQ, 7).Build()
+	if !strings.Contains(sql, "WHERE 1=0") {
+		t.Fatalf("expected guard despite scope, got: %s", sql)
+	}
+	sql2, _ := NewQB().Delete("t").Where("id", EQ, 1).OrWhere("id", EQ, 2).Scope("tenant_id", EQ, 7).Build()
+	if sql2 != "DELETE FROM t WHERE (id = $1 OR id = $2) AND tenant_id = $3" {
+		t.Fatalf("unexpected sql: %s", sql2)
+	}
+}
+
+func TestSubqueries_SharedPlaceholderStream(t *testing.T) {
+	banned := NewQB().Select("user_id").From("bans").Where("reason", EQ, "spam")
+	orders := NewQB().Select("user_id").From("orders").
+		Where("total", GT, 100).
+		WhereNotInSub("user_id", banned)
+
+	sql, args := NewQB().
+		Select("id").
+		From("users").
+		Where("active", EQ, true).
+		WhereInSub("id", orders).
+		Where("age", GTE, 18).
+		Build()
+
+	want := "SELECT id FROM users WHERE active = $1 AND id IN (SELECT user_id FROM orders WHERE total > $2 AND user_id NOT IN (SELECT user_id FROM bans WHERE reason = $3)) AND age >= $4"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, 100, "spam", 18}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	// sub-builders are not reset by the parent's Build and can be reused
+	sql, args = orders.Build()
+	if sql != "SELECT user_id FROM orders WHERE total > $1 AND user_id NOT IN (SELECT user_id FROM bans WHERE reason = $2)" {
+		t.Fatalf("unexpected reused sub sql: %s", sql)
+	}
+	if len(args) != 2 {
+		t.Fatalf("unexpected reused sub args: %#v", args)
+	}
+}
+
+func TestWhereExists_DistinctAndDialect(t *testing.T) {
+	sub := NewQB().Select("o.user_id").Distinct().From("orders o").Where("o.status", EQ, "paid")
+
+	sql, args := NewQB().WithDialect(MySQL).WithQuoting(true).
+		Select("id").
+		From("users").
+		Where("id", GT, 10).
+		WhereExists(sub).
+		Build()
+
+	want := "SELECT DISTINCT `o`.`user_id` FROM `orders` `o` WHERE `o`.`status` = ?"
+	if !strings.Contains(sql, "EXISTS ("+want+")") {
+		t.Fatalf("sql mismatch:\n got: %s\nwant EXISTS (%s)", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{10, "paid"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().Select("id").From("users").WhereNotExists(sub).Build()
+	if sql != "SELECT id FROM users WHERE NOT EXISTS (SELECT DISTINCT o.user_id FROM orders o WHERE o.status = $1)" {
+		t.Fatalf("unexpected NOT EXISTS sql: %s", sql)
+	}
+
+	// an explicit WithQuoting(false) on the sub-builder wins over the parent
+	unquoted := NewQB().WithQuoting(false).Select("1").From("orders o").WhereColumn("o.user_id", EQ, "u.id")
+	sql, _ = NewQB().WithQuoting(true).Select("id").From("users u").WhereExists(unquoted).Build()
+	want = `SELECT "id" FROM "users" "u" WHERE EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id)`
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestWhereExists_Correlated(t *testing.T) {
+	sub := NewQB().Select("1").From("orders o").
+		WhereColumn("o.user_id", EQ, "u.id").
+		WhereRaw("o.created_at > NOW() - ?::interval", "30 days").
+		Where("o.total", GT, 50)
+
+	sql, args := NewQB().
+		Select("u.id").
+		From("users u").
+		Where("u.active", EQ, true).
+		WhereExists(sub).
+		Build()
+
+	want := "SELECT u.id FROM users u WHERE u.active = $1 AND EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id AND o.created_at > NOW() - $2::interval AND o.total > $3)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, "30 days", 50}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	// column references are quoted like identifiers, raw predicates never are
+	sql, _ = NewQB().WithQuoting(true).Select("id").From("users u").
+		WhereColumn("u.id", EQ, "u.owner_id").
+		OrWhereRaw("data ?? 'k'").
+		Build()
+	want = `SELECT "id" FROM "users" "u" WHERE "u"."id" = "u"."owner_id" OR data ? 'k'`
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestWhereRaw_ArgCountMismatch(t *testing.T) {
+	_, _, err := NewQB().Select("id").From("users").WhereRaw("a = ? AND b = ?", 1).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "2 placeholders but 1 args") {
+		t.Fatalf("expected placeholder mismatch error, got: %v", err)
+	}
+}
+
+func TestWhereArrayLen(t *testing.T) {
+	sql, args := NewQB().Select("id").From("posts").WhereArrayLen("tags", GT, 2).Build()
+	want := "SELECT id FROM posts WHERE cardinality(tags) > $1"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{2}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().WithQuoting(true).Select("id").From("posts").WhereArrayLen("p.tags", EQ, 0).Build()
+	if sql != `SELECT "id" FROM "posts" WHERE cardinality("p"."tags") = $1` {
+		t.Fatalf("unexpected quoted sql: %s", sql)
+	}
+
+	sql, _ = NewQB().WithDialect(MySQL).Select("id").From("posts").WhereArrayLen("tags", GTE, 1).Build()
+	if sql != "SELECT id FROM posts WHERE JSON_LENGTH(tags) >= ?" {
+		t.Fatalf("unexpected MySQL sql: %s", sql)
+	}
+}
+
+func TestUpsertReturning(t *testing.T) {
+	data := map[string]interface{}{"email": "a@x.io", "name": "Alice", "age": 30}
+	sql, args, err := NewQB().UpsertReturning("users", data, []string{"email"}, "id").BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "INSERT INTO users (age, email, name) VALUES ($1, $2, $3) ON CONFLICT (email) DO UPDATE SET age = excluded.age, name = excluded.name RETURNING id"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{30, "a@x.io", "Alice"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	q := NewQB().WithDialect(MySQL).UpsertReturning("users", data, []string{"email"}, "id")
+	_, _, err = q.Clone().BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "RETURNING is not supported on MySQL") ||
+		!strings.Contains(err.Error(), "ON CONFLICT is not supported on MySQL") {
+		t.Fatalf("expected MySQL errors, got: %v", err)
+	}
+	// without strict mode both clauses are dropped
+	if sql, _ := q.Build(); sql != "INSERT INTO users (age, email, name) VALUES (?, ?, ?)" {
+		t.Fatalf("unexpected MySQL sql: %s", sql)
+	}
+}
+
+func TestHavingExpr_NotQuoted(t *testing.T) {
+	sql, args := NewQB().WithQuoting(true).
+		Select("customer_id", "region").
+		SelectRaw("SUM(amount)").
+		From("orders").
+		GroupBy("customer_id", "region").
+		HavingExpr("SUM(amount)", GT, 1000).
+		HavingExpr("COUNT(*)", GTE, 3).
+		Build()
+
+	want := `SELECT "customer_id", "region", SUM(amount) FROM "orders" GROUP BY "customer_id", "region" HAVING SUM(amount) > $1 AND COUNT(*) >= $2`
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1000, 3}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestRenumber(t *testing.T) {
+	frag := "a = ? AND note = 'why?' AND \"odd?col\" = ? AND data ?? 'k'"
+
+	got, next := Renumber(frag, 3, DollarN)
+	want := "a = $3 AND note = 'why?' AND \"odd?col\" = $4 AND data ? 'k'"
+	if got != want || next != 5 {
+		t.Fatalf("DollarN mismatch:\n got: %s (next %d)\nwant: %s (next 5)", got, next, want)
+	}
+
+	got, next = Renumber(frag, 1, QuestionMark)
+	want = "a = ? AND note = 'why?' AND \"odd?col\" = ? AND data ?? 'k'"
+	if got != want || next != 3 {
+		t.Fatalf("QuestionMark mismatch:\n got: %s (next %d)\nwant: %s (next 3)", got, next, want)
+	}
+
+	got, next = Renumber("x = 'it''s ?' OR y = ?", 1, DollarN)
+	if got != "x = 'it''s ?' OR y = $1" || next != 2 {
+		t.Fatalf("escaped quote mismatch: %s (next %d)", got, next)
+	}
+}
+
+func TestHint_AfterSelect(t *testing.T) {
+	sql, _ := NewQB().WithDialect(MySQL).
+		Select("id", "email").
+		Distinct().
+		Hint("INDEX(users idx_users_email)").
+		Hint("MAX_EXECUTION_TIME(1000)").
+		From("users").
+		Where("email", EQ, "a@x.io").
+		Build()
+
+	want := "SELECT /*+ INDEX(users idx_users_email) MAX_EXECUTION_TIME(1000) */ DISTINCT id, email FROM users WHERE email = ?"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	sql, _ = NewQB().Select("id").From("t").Hint("SeqScan(t) */ DROP TABLE t; /*").Build()
+	if strings.Count(sql, "*/") != 1 {
+		t.Fatalf("hint escaped its comment: %s", sql)
+	}
+
+	for _, evil := range []string{"/*/ evil */", "*/*", "**//", "/", "x/"} {
+		sql, _ = NewQB().Select("id").From("t").Hint(evil).Build()
+		if strings.Count(sql, "*/") != 1 || strings.Count(sql, "/*") != 1 || !strings.HasSuffix(sql, "*/ id FROM t") {
+			t.Fatalf("hint %q escaped its comment: %s", evil, sql)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := NewQB().Select("id").From("users").Where("age", GT, 18).Where("name", EQ, "bob")
+	b := NewQB().Select("id").From("users").Where("age", GT, int64(18)).Where("name", EQ, "bob")
+
+	fa := a.Fingerprint()
+	if fa != b.Fingerprint() {
+		t.Fatalf("equivalent builders should share a fingerprint")
+	}
+	if len(fa) != 64 {
+		t.Fatalf("expected sha-256 hex, got %q", fa)
+	}
+
+	// Fingerprint does not consume the builder
+	if fa != a.Fingerprint() {
+		t.Fatalf("fingerprint changed on second call")
+	}
+	if sql, args := a.Build(); sql != "SELECT id FROM users WHERE age > $1 AND name = $2" || len(args) != 2 {
+		t.Fatalf("builder altered by Fingerprint: %s %#v", sql, args)
+	}
+
+	c := NewQB().Select("id").From("users").Where("age", GT, 21).Where("name", EQ, "bob")
+	if c.Fingerprint() == fa {
+		t.Fatalf("different params should change the fingerprint")
+	}
+	d := NewQB().Select("id").From("users").Where("age", GT, "18").Where("name", EQ, "bob")
+	if d.Fingerprint() == fa {
+		t.Fatalf("string and int params should not collide")
+	}
+
+}
+
+func TestFingerprint_PointersAndValuers(t *testing.T) {
+	x, y := 18, 18
+	px := NewQB().Select("id").From("users").Where("age", GT, &x).Fingerprint()
+	py := NewQB().Select("id").From("users").Where("age", GT, &y).Fingerprint()
+	if px != py {
+		t.Fatalf("pointers to equal values should share a fingerprint")
+	}
+	if pv := NewQB().Select("id").From("users").Where("age", GT, 18).Fingerprint(); pv != px {
+		t.Fatalf("a pointer should hash like its value")
+	}
+
+	var nilPtr *int
+	if NewQB().Select("id").From("t").Where("a", EQ, nilPtr).Fingerprint() !=
+		NewQB().Select("id").From("t").Where("a", EQ, nil).Fingerprint() {
+		t.Fatalf("a nil pointer should hash like nil")
+	}
+
+	m1, m2 := debugMoney{cents: 1050}, debugMoney{cents: 1050}
+	if NewQB().Select("id").From("t").Where("price", EQ, &m1).Fingerprint() !=
+		NewQB().Select("id").From("t").Where("price", EQ, m2).Fingerprint() {
+		t.Fatalf("valuers should hash by their driver value")
+	}
+	if NewQB().Select("id").From("t").Where("price", EQ, debugMoney{cents: 1}).Fingerprint() ==
+		NewQB().Select("id").From("t").Where("price", EQ, m2).Fingerprint() {
+		t.Fatalf("different valuer values should change the fingerprint")
+	}
+}
+
+func TestWhereDateParts(t *testing.T) {
+	day := time.Date(2024, 3, 9, 15, 4, 5, 0, time.UTC)
+
+	sql, args := NewQB().Select("id").From("orders").
+		WhereYear("created_at", EQ, 2024).
+		WhereMonth("created_at", GTE, 3).
+		WhereDate("shipped_at", LT, day).
+		Build()
+	want := "SELECT id FROM orders WHERE EXTRACT(YEAR FROM created_at) = $1 AND EXTRACT(MONTH FROM created_at) >= $2 AND date(shipped_at) < $3"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{2024, 3, "2024-03-09"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().WithDialect(MySQL).Select("id").From("orders").
+		WhereYear("created_at", EQ, 2024).
+		WhereMonth("created_at", EQ, 3).
+		WhereDate("created_at", EQ, day).
+		Build()
+	want = "SELECT id FROM orders WHERE YEAR(created_at) = ? AND MONTH(created_at) = ? AND DATE(created_at) = ?"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestInsert_SQLiteDefaultValuesReturning(t *testing.T) {
+	sql, args := NewQB().WithDialect(SQLite).Insert("events").Returning("id", "created_at").Build()
+	want := "INSERT INTO events DEFAULT VALUES RETURNING id, created_at"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 0 {
+		t.Fatalf("expected no args, got: %#v", args)
+	}
+
+	sql, _ = NewQB().WithDialect(SQLite).Insert("events").Set("kind", "x").
+		OnConflict("kind").OnConflictDoNothing().Returning("id").Build()
+	want = "INSERT INTO events (kind) VALUES (?) ON CONFLICT (kind) DO NOTHING RETURNING id"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	sql, _ = NewQB().WithDialect(MySQL).Insert("events").Returning("id").Build()
+	if sql != "INSERT INTO events () VALUES ()" {
+		t.Fatalf("unexpected MySQL sql: %s", sql)
+	}
+}
+
+func TestValuesBatch(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "a"},
+		{"id": 2, "name": "b"},
+	}
+	sql, args := NewQB().Insert("users").ValuesBatch(rows).Returning("id").Build()
+	want := "INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4) RETURNING id"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "a", 2, "b"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	_, _, err := NewQB().Insert("users").ValuesBatch([]map[string]interface{}{{"id": 1}, {"name": "x"}}).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "row 1") {
+		t.Fatalf("expected column mismatch error, got: %v", err)
+	}
+}
+
+func TestBuildBatches_Chunks(t *testing.T) {
+	rows := make([]map[string]interface{}, 250)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": "n" + strconv.Itoa(i)}
+	}
+
+	b := NewQB().Insert("users").OnConflict("id").OnConflictDoNothing()
+	batches := b.BuildBatches(rows, 100)
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(batches))
+	}
+	for i, wantRows := range []int{100, 100, 50} {
+		if got := len(batches[i].Args); got != wantRows*2 {
+			t.Fatalf("batch %d: expected %d args, got %d", i, wantRows*2, got)
+		}
+		if !strings.HasPrefix(batches[i].SQL, "INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4)") ||
+			!strings.HasSuffix(batches[i].SQL, "($"+strconv.Itoa(wantRows*2-1)+", $"+strconv.Itoa(wantRows*2)+") ON CONFLICT (id) DO NOTHING") {
+			t.Fatalf("batch %d: unexpected sql: %.80s...", i, batches[i].SQL)
+		}
+	}
+	if batches[2].Args[0] != 200 {
+		t.Fatalf("last batch should start at row 200, got %v", batches[2].Args[0])
+	}
+	if b.Table != "" {
+		t.Fatalf("expected builder reset after BuildBatches")
+	}
+}
+
+func TestReturning_DroppedForMySQL(t *testing.T) {
+	sql, _ := NewQB().WithDialect(MySQL).Update("users").SetUpdate("name", "x").
+		Where("id", EQ, 1).Returning("id").Build()
+	if sql != "UPDATE users SET name = ? WHERE id = ?" {
+		t.Fatalf("unexpected MySQL update: %s", sql)
+	}
+
+	sql, _, err := NewQB().WithDialect(MySQL).Delete("users").Where("id", EQ, 1).Returning().BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "RETURNING is not supported") {
+		t.Fatalf("expected strict-mode RETURNING error, got: %v (%s)", err, sql)
+	}
+	sql, _ = NewQB().WithDialect(MySQL).Delete("users").Where("id", EQ, 1).Returning().Build()
+	if sql != "DELETE FROM users WHERE id = ?" {
+		t.Fatalf("unexpected MySQL delete: %s", sql)
+	}
+
+	sql, _ = NewQB().WithDialect(SQLite).Delete("users").Where("id", EQ, 1).Returning("id").Build()
+	if sql != "DELETE FROM users WHERE id = ? RETURNING id" {
+		t.Fatalf("unexpected SQLite delete: %s", sql)
+	}
+}
+
+func TestWhereValueBetweenColumns(t *testing.T) {
+	sql, args := NewQB().Select("id").From("prices").
+		Where("sku", EQ, "A1").
+		WhereValueBetweenColumns("2024-05-01", "valid_from", "valid_to").
+		Build()
+	want := "SELECT id FROM prices WHERE sku = $1 AND $2 BETWEEN valid_from AND valid_to"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"A1", "2024-05-01"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestWhereTrueFalse(t *testing.T) {
+	sql, args := NewQB().Select("id").From("users").WhereFalse().OrWhere("role", EQ, "admin").Build()
+	want := "SELECT id FROM users WHERE (1=0) OR role = $1"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"admin"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().Select("id").From("users").WhereTrue().OrWhere("role", EQ, "admin").Build()
+	if sql != "SELECT id FROM users WHERE (1=1) OR role = $1" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+}
+
+func TestOrderByExpr_NotQuoted(t *testing.T) {
+	sql, _ := NewQB().WithQuoting(true).
+		Select("country").
+		SelectRaw("COUNT(*) AS cnt").
+		From("users").
+		GroupBy("country").
+		OrderByExpr("COUNT(*)", true).
+		OrderBy("country").
+		Build()
+	want := `SELECT "country", COUNT(*) AS cnt FROM "users" GROUP BY "country" ORDER BY COUNT(*) DESC, "country" ASC`
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestEntryPoints_PreserveConfig(t *testing.T) {
+	b := NewQB().WithDialect(MySQL).WithQuoting(true).WithTablePrefix("t1_")
+
+	// abandoned, unbuilt statement state must not leak into the next one
+	b.Update("users").SetUpdate("name", "x").Where("id", EQ, 1)
+	sql, args := b.Delete("sessions").Where("user_id", EQ, 9).Build()
+	if sql != "DELETE FROM `t1_sessions` WHERE `user_id` = ?" {
+		t.Fatalf("unexpected delete: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{9}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+	if b.dialect() != MySQL || !b.QuoteIdents || b.TablePrefix != "t1_" {
+		t.Fatalf("configuration lost after Delete: %#v", b)
+	}
+
+	b.Insert("users").Set("name", "y")
+	sql, _ = b.Select("id").From("users").Where("id", EQ, 1).Build()
+	if sql != "SELECT `id` FROM `t1_users` WHERE `id` = ?" {
+		t.Fatalf("unexpected select: %s", sql)
+	}
+
+	// SELECT parts and scopes chained before the entry point are kept
+	sql, _ = NewQB().From("users").Where("a", EQ, 1).Select("id").Build()
+	if sql != "SELECT id FROM users WHERE a = $1" {
+		t.Fatalf("unexpected select: %s", sql)
+	}
+	sql, _ = NewQB().Scope("tenant_id", EQ, 7).Update("users").SetUpdate("x", 1).Where("id", EQ, 2).Build()
+	if sql != "UPDATE users SET x = $1 WHERE id = $2 AND tenant_id = $3" {
+		t.Fatalf("scope dropped by Update: %s", sql)
+	}
+}
+
+func TestJoinIf(t *testing.T) {
+	build := func(byCity bool) string {
+		sql, _ := NewQB().Select("u.id").From("users u").
+			JoinIf(byCity, "addresses a", "a.user_id = u.id").
+			LeftJoinIf(byCity, "cities c", "c.id = a.city_id").
+			Build()
+		return sql
+	}
+
+	if got := build(false); got != "SELECT u.id FROM users u" {
+		t.Fatalf("false condition added a join: %s", got)
+	}
+	want := "SELECT u.id FROM users u INNER JOIN addresses a ON a.user_id = u.id LEFT JOIN cities c ON c.id = a.city_id"
+	if got := build(true); got != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestWithComment(t *testing.T) {
+	b := NewQB().WithComment(map[string]string{"service": "billing", "op": "invoice_list"})
+	sql, _ := b.Select("id").From("invoices").Where("id", EQ, 1).Build()
+	want := "/* op=invoice_list,service=billing */ SELECT id FROM invoices WHERE id = $1"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	// survives Reset like other builder configuration
+	sql, _ = b.Delete("invoices").Where("id", EQ, 2).Build()
+	if !strings.HasPrefix(sql, "/* op=invoice_list,service=billing */ DELETE FROM invoices") {
+		t.Fatalf("comment lost after Reset: %s", sql)
+	}
+
+	sql, _ = NewQB().WithComment(map[string]string{"op": "x */ DROP TABLE users; /* y"}).
+		Select("id").From("t").Build()
+	if strings.Count(sql, "*/") != 1 || strings.Count(sql, "/*") != 1 || !strings.HasSuffix(sql, "*/ SELECT id FROM t") {
+		t.Fatalf("comment value broke out: %s", sql)
+	}
+
+	for _, evil := range []string{"/*/ DELETE FROM users; --", "*/*", "**//"} {
+		sql, _ = NewQB().WithComment(map[string]string{"op": evil}).Select("id").From("t").Build()
+		if strings.Count(sql, "*/") != 1 || strings.Count(sql, "/*") != 1 || !strings.HasSuffix(sql, "*/ SELECT id FROM t") {
+			t.Fatalf("comment value %q broke out: %s", evil, sql)
+		}
+	}
+	sql, _ = NewQB().WithComment(map[string]string{"op": "/*/"}).Select("id").From("t").Build()
+	if want := "/* op=/ * / */ SELECT id FROM t"; sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestOnConflictAuto_CompositeKey(t *testing.T) {
+	b := NewQB().WithPrimaryKey("tenant_id", "id")
+	sql, args, err := b.Insert("items").
+		Values(map[string]interface{}{"tenant_id": 1, "id": 2, "name": "x"}).
+		OnConflictAuto().
+		OnConflictSetExcluded("name").
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "INSERT INTO items (id, name, tenant_id) VALUES ($1, $2, $3) ON CONFLICT (tenant_id, id) DO UPDATE SET name = excluded.name"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 3 {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+	if !reflect.DeepEqual(b.PrimaryKey, []string{"tenant_id", "id"}) {
+		t.Fatalf("primary key should survive Build: %#v", b.PrimaryKey)
+	}
+
+	_, _, err = NewQB().Insert("items").Set("id", 1).OnConflictAuto().OnConflictDoNothing().BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "WithPrimaryKey") {
+		t.Fatalf("expected missing key error, got: %v", err)
+	}
+}
+
+func TestMergeWhere_Policy(t *testing.T) {
+	policy := NewQB().
+		Join("memberships m", "m.project_id = p.id").
+		Where("m.user_id", EQ, 42).
+		OrWhere("p.public", EQ, true)
+
+	sql, args := NewQB().Select("p.id").From("projects p").
+		Join("memberships m", "m.project_id = p.id").
+		Where("p.name", LIKE, "a%").
+		OrWhere("p.archived", EQ, false).
+		MergeWhere(policy).
+		Build()
+
+	want := "SELECT p.id FROM projects p INNER JOIN memberships m ON m.project_id = p.id WHERE (p.name LIKE $1 OR p.archived = $2) AND (m.user_id = $3 OR p.public = $4)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"a%", false, 42, true}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+	if len(policy.Conditions) != 2 {
+		t.Fatalf("policy builder should be untouched")
+	}
+
+	sql, _ = NewQB().Select("id").From("docs").MergeWhere(NewQB().Where("owner_id", EQ, 1).Join("x", "x.id = docs.x_id")).Build()
+	if sql != "SELECT id FROM docs INNER JOIN x ON x.id = docs.x_id WHERE owner_id = $1" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+}
+
+func TestSelectExpr_ParamsBeforeWhere(t *testing.T) {
+	sql, args, err := NewQB().WithQuoting(true).
+		Select("id").
+		SelectExpr("price * ?", "discounted", 0.9).
+		From("products").
+		Where("category", EQ, "books").
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `SELECT "id", price * $1 AS discounted FROM "products" WHERE "category" = $2`
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{0.9, "books"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestWhereNotGroup(t *testing.T) {
+	sql, args := NewQB().Select("id").From("users").
+		Where("active", EQ, true).
+		WhereNotGroup(func(g *QueryBuilder) {
+			g.Where("a", EQ, 1).OrWhere("b", EQ, 2)
+		}).
+		Where("c", EQ, 3).
+		Build()
+	want := "SELECT id FROM users WHERE active = $1 AND NOT (a = $2 OR b = $3) AND c = $4"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, 1, 2, 3}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestSelect_ReplacesProjection(t *testing.T) {
+	cases := []struct {
+		name string
+		b    *QueryBuilder
+		want string
+	}{
+		{"no args", NewQB().Select(), "SELECT * FROM t"},
+		{"no args after columns", NewQB().Select("a").Select(), "SELECT * FROM t"},
+		{"columns replace", NewQB().Select("a").Select("b", "c"), "SELECT b, c FROM t"},
+		{"replaces raw", NewQB().SelectRaw("COUNT(*)").Select("a"), "SELECT a FROM t"},
+		{"raw appends", NewQB().Select("a").SelectRaw("COUNT(*)"), "SELECT a, COUNT(*) FROM t"},
+	}
+	for _, tc := range cases {
+		if sql, _ := tc.b.From("t").Build(); sql != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.name, sql, tc.want)
+		}
+	}
+}
+
+type debugMoney struct{ cents int64 }
+
+func (m debugMoney) Value() (driver.Value, error) {
+	return fmt.Sprintf("%d.%02d", m.cents/100, m.cents%100), nil
+}
+
+func TestDebugSQL_Literals(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	b := NewQB().Select("id").From("orders").
+		Where("created_at", GT, ts).
+		Where("note", EQ, sql.NullString{}).
+		Where("total", EQ, debugMoney{cents: 1234}).
+		Where("name", EQ, "O'Brien")
+	got := b.DebugSQL()
+	want := "SELECT id FROM orders WHERE created_at > TIMESTAMP '2024-01-02 15:04:05' AND note = NULL AND total = '12.34' AND name = 'O''Brien'"
+	if got != want {
+		t.Fatalf("debug mismatch:\n got: %s\nwant: %s", got, want)
+	}
+	if sql, args := b.Build(); !strings.Contains(sql, "$4") || len(args) != 4 {
+		t.Fatalf("DebugSQL consumed the builder: %s %#v", sql, args)
+	}
+
+	got = NewQB().WithDialect(MySQL).Select("id").From("orders").
+		Where("created_at", GT, ts).Where("flag", EQ, true).DebugSQL()
+	want = "SELECT id FROM orders WHERE created_at > '2024-01-02 15:04:05' AND flag = TRUE"
+	if got != want {
+		t.Fatalf("debug mismatch:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestWhereInSub_JoinedSubquery(t *testing.T) {
+	sub := NewQB().Select("o.user_id").From("orders o").
+		Join("order_items i", "i.order_id = o.id").
+		LeftJoin("refunds r", "r.order_id = o.id").
+		Where("i.sku", EQ, "X1").
+		WhereIn("o.status", []string{"paid", "shipped"}).
+		WhereNull("r.id").
+		GroupBy("o.user_id").
+		HavingExpr("COUNT(*)", GT, 2)
+
+	sql, args := NewQB().WithQuoting(true).
+		Select("id").From("users").
+		Where("active", EQ, true).
+		WhereInSub("id", sub).
+		Where("created_at", GT, "2024-01-01").
+		Build()
+
+	want := `SELECT "id" FROM "users" WHERE "active" = $1 AND "id" IN (` +
+		`SELECT "o"."user_id" FROM "orders" "o" INNER JOIN "order_items" "i" ON i.order_id = o.id LEFT JOIN "refunds" "r" ON r.order_id = o.id ` +
+		`WHERE "i"."sku" = $2 AND "o"."status" IN ($3, $4) AND "r"."id" IS NULL GROUP BY "o"."user_id" HAVING COUNT(*) > $5` +
+		`) AND "created_at" > $6`
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, "X1", "paid", "shipped", 2, "2024-01-01"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestGetters_ReturnCopies(t *testing.T) {
+	b := NewQB().Select("id", "name").From("users").
+		Where("a", EQ, 1).
+		WhereGroup(func(g *QueryBuilder) { g.Where("b", EQ, 2).OrWhere("c", EQ, 3) })
+
+	cols := b.GetColumns()
+	cols[0] = "password"
+	conds := b.GetConditions()
+	conds[0].Value = 99
+	conds[1].Group[0].Column = "hacked"
+
+	sql, args := b.Clone().Build()
+	want := "SELECT id, name FROM users WHERE a = $1 AND (b = $2 OR c = $3)"
+	if sql != want {
+		t.Fatalf("builder changed through getters:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 2, 3}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	// rewrite pipeline: read, transform, write back
+	rewritten := append(b.GetConditions(), Condition{Column: "tenant_id", Op: EQ, Value: 7, Logic: "AND"})
+	sql, _ = b.SetConditions(rewritten).Build()
+	if sql != want+" AND tenant_id = $4" {
+		t.Fatalf("unexpected rewritten sql: %s", sql)
+	}
+}
+
+func TestDeleteLimited(t *testing.T) {
+	sql, args := NewQB().Delete("jobs").
+		Where("status", EQ, "done").
+		OrderBy("finished_at").
+		DeleteLimited(500).
+		Returning("id").
+		Build()
+	want := "DELETE FROM jobs WHERE id IN (SELECT id FROM jobs WHERE status = $1 ORDER BY finished_at ASC LIMIT $2) RETURNING id"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"done", 500}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().WithPrimaryKey("tenant_id", "id").Delete("items").Where("stale", EQ, true).DeleteLimited(10).Build()
+	if sql != "DELETE FROM items WHERE (tenant_id, id) IN (SELECT tenant_id, id FROM items WHERE stale = $1 LIMIT $2)" {
+		t.Fatalf("unexpected composite-key sql: %s", sql)
+	}
+
+	sql, _ = NewQB().WithDialect(MySQL).Delete("jobs").Where("status", EQ, "done").DeleteLimited(500).Build()
+	if sql != "DELETE FROM jobs WHERE status = ? LIMIT ?" {
+		t.Fatalf("unexpected MySQL sql: %s", sql)
+	}
+
+	// the write guard still applies
+	sql, args = NewQB().Delete("jobs").DeleteLimited(500).Build()
+	if sql != "DELETE FROM jobs WHERE 1=0 /*guarded: mising WHERE */" || len(args) != 0 {
+		t.Fatalf("guard bypassed: %s %#v", sql, args)
+	}
+}
+
+func TestCrossJoinAndJoinRaw(t *testing.T) {
+	sql, _ := NewQB().Select("*").From("a").
+		Join("b", "b.a_id = a.id").
+		JoinRaw("NATURAL JOIN c").
+		CrossJoin("d").
+		JoinRaw("JOIN e USING (k)").
+		Build()
+	want := "SELECT * FROM a INNER JOIN b ON b.a_id = a.id NATURAL JOIN c CROSS JOIN d JOIN e USING (k)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestOrderByManyAndCols(t *testing.T) {
+	sql, _ := NewQB().Select("*").From("t").OrderByMany(
+		OrderBy{Column: "priority", Desc: true},
+		OrderBy{Column: "due_at", Nulls: NullsLast},
+		OrderBy{Column: "id", Desc: true},
+	).Build()
+	want := "SELECT * FROM t ORDER BY priority DESC, due_at ASC NULLS LAST, id DESC"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	sql, _ = NewQB().Select("*").From("t").OrderByCols("a", "b").Build()
+	if sql != "SELECT * FROM t ORDER BY a ASC, b ASC" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+}
+
+func TestGroupByRollup_WithGrouping(t *testing.T) {
+	sql, args, err := NewQB().
+		Select("region", "product").
+		SelectRaw("SUM(amount) AS total").
+		SelectGrouping("region", "is_region_total").
+		From("sales").
+		Where("year", EQ, 2024).
+		GroupByRollup("region", "product").
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "SELECT region, product, SUM(amount) AS total, GROUPING(region) AS is_region_total FROM sales WHERE year = $1 GROUP BY ROLLUP (region, product)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 1 {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().WithDialect(MySQL).Select("region").SelectGrouping("region", "g").
+		From("sales").GroupByRollup("region").Build()
+	if sql != "SELECT region, GROUPING(region) AS g FROM sales GROUP BY region WITH ROLLUP" {
+		t.Fatalf("unexpected MySQL sql: %s", sql)
+	}
+
+	_, _, err = NewQB().WithDialect(SQLite).Select("region").From("sales").GroupByRollup("region").BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "ROLLUP") {
+		t.Fatalf("expected SQLite ROLLUP error, got: %v", err)
+	}
+}
+
+func TestWrap_FilterWindowAlias(t *testing.T) {
+	inner := NewQB().
+		Select("id", "user_id").
+		SelectRaw("ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC) AS rn").
+		From("orders").
+		Where("status", EQ, "paid")
+
+	sql, args := inner.Wrap("ranked").Where("rn", EQ, 1).Where("user_id", IN, []int{7, 8}).Build()
+	want := "SELECT * FROM (SELECT id, user_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC) AS rn FROM orders WHERE status = $1) AS ranked WHERE rn = $2 AND user_id IN ($3, $4)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"paid", 1, 7, 8}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	// the wrapped builder is untouched
+	if sql, _ := inner.Build(); !strings.HasPrefix(sql, "SELECT id, user_id, ROW_NUMBER()") {
+		t.Fatalf("inner builder changed: %s", sql)
+	}
+}
+
+func TestWhereInVals(t *testing.T) {
+	cases := []struct {
+		vals []interface{}
+		want string
+	}{
+		{nil, "SELECT id FROM t WHERE (1=0)"},
+		{[]interface{}{5}, "SELECT id FROM t WHERE id IN ($1)"},
+		{[]interface{}{1, "two", 3}, "SELECT id FROM t WHERE id IN ($1, $2, $3)"},
+	}
+	for _, tc := range cases {
+		sql, args := NewQB().Select("id").From("t").WhereInVals("id", tc.vals...).Build()
+		if sql != tc.want {
+			t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, tc.want)
+		}
+		if len(args) != len(tc.vals) {
+			t.Fatalf("args mismatch for %v: %#v", tc.vals, args)
+		}
+	}
+}
+
+func TestWhereInScalar_StrictError(t *testing.T) {
+	_, _, err := NewQB().Select("id").From("t").WhereIn("id", 5).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "WhereIn requires a slice/array, got int") {
+		t.Fatalf("expected scalar IN error, got: %v", err)
+	}
+	_, _, err = NewQB().Select("id").From("t").WhereNotIn("id", "x").BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "WhereNotIn requires a slice/array, got string") {
+		t.Fatalf("expected scalar NOT IN error, got: %v", err)
+	}
+	if _, _, err = NewQB().Select("id").From("t").WhereIn("id", []int{}).BuildErr(); err != nil {
+		t.Fatalf("empty slice should stay valid: %v", err)
+	}
+}
+
+func TestBuildNamed_RepeatedName(t *testing.T) {
+	b := NewQB().Select("p.id").From("projects p").
+		Join("members m", "m.project_id = p.id").
+		Where("p.tenant_id", EQ, Named("tenant", 7)).
+		Where("m.tenant_id", EQ, Named("tenant", 7)).
+		Where("p.status", EQ, "open")
+
+	sql, args, err := b.Clone().BuildNamed()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "SELECT p.id FROM projects p INNER JOIN members m ON m.project_id = p.id WHERE p.tenant_id = :tenant AND m.tenant_id = :tenant AND p.status = :p3"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, map[string]interface{}{"tenant": 7, "p3": "open"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	// positional builds bind the wrapped value
+	_, pos := b.Build()
+	if !reflect.DeepEqual(pos, []interface{}{7, 7, "open"}) {
+		t.Fatalf("positional args mismatch: %#v", pos)
+	}
+
+	_, _, err = NewQB().Select("id").From("t").
+		Where("a", EQ, Named("x", 1)).Where("b", EQ, Named("x", 2)).BuildNamed()
+	if err == nil || !strings.Contains(err.Error(), `"x"`) {
+		t.Fatalf("expected conflicting name error, got: %v", err)
+	}
+}
+
+func TestWithStatementTimeout(t *testing.T) {
+	sql, _ := NewQB().WithStatementTimeout(500 * time.Millisecond).
+		WithComment(map[string]string{"op": "list"}).
+		Select("id").From("t").Build()
+	want := "/* op=list,statement_timeout=500ms */ SELECT id FROM t"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	sql, _ = NewQB().WithStatementTimeout(2*time.Second).Delete("t").Where("id", EQ, 1).Build()
+	if sql != "/* statement_timeout=2000ms */ DELETE FROM t WHERE id = $1" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+
+	sql, _ = NewQB().WithDialect(MySQL).WithStatementTimeout(time.Second).Select("id").From("t").Build()
+	if sql != "SELECT /*+ MAX_EXECUTION_TIME(1000) */ id FROM t" {
+		t.Fatalf("unexpected MySQL sql: %s", sql)
+	}
+}
+
+func TestInsertDefaultKeyword(t *testing.T) {
+	sql, args := NewQB().Insert("t").Set("a", 1).Set("b", Default()).Build()
+	if sql != "INSERT INTO t (a, b) VALUES ($1, DEFAULT)" {
+		t.Fatalf("sql = %q", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1}) {
+		t.Fatalf("args = %v", args)
+	}
+
+	sql, args = NewQB().WithPlaceholders(QuestionMark).Insert("t").ValuesBatch([]map[string]interface{}{
+		{"a": 1, "b": Default()},
+		{"a": 2, "b": "x"},
+	}).Build()
+	if sql != "INSERT INTO t (a, b) VALUES (?, DEFAULT), (?, ?)" {
+		t.Fatalf("batch sql = %q", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 2, "x"}) {
+		t.Fatalf("batch args = %v", args)
+	}
+}
+
+func TestAffectedColumns(t *testing.T) {
+	u := NewQB().Update("users").SetUpdate("name", "a").SetUpdate("email", "b").Where("id", EQ, 1)
+	if got := u.UpdatedColumns(); !reflect.DeepEqual(got, []string{"email", "name"}) {
+		t.Fatalf("UpdatedColumns = %v", got)
+	}
+	if sql, _ := u.Build(); sql != "UPDATE users SET email = $1, name = $2 WHERE id = $3" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+
+	i := NewQB().Insert("users").Set("z", 1).Set("a", 2)
+	if got := i.InsertedColumns(); !reflect.DeepEqual(got, []string{"a", "z"}) {
+		t.Fatalf("InsertedColumns = %v", got)
+	}
+
+	i = NewQB().Insert("users").ValuesBatch([]map[string]interface{}{{"b": 1}, {"a": 2}})
+	if got := i.InsertedColumns(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("batch InsertedColumns = %v", got)
+	}
+}
+
+func TestMergeKey_BothDialects(t *testing.T) {
+	upsert := func(qb *QueryBuilder) *QueryBuilder {
+		return qb.Insert("users").
+			Values(map[string]interface{}{"email": "a@x.io", "name": "Alice"}).
+			MergeKey("email").
+			OnConflictSetExcluded("name").
+			OnConflictSet("hits", RawExpr("hits + 1"))
+	}
+
+	sql, args := upsert(NewQB()).Build()
+	want := "INSERT INTO users (email, name) VALUES ($1, $2) ON CONFLICT (email) DO UPDATE SET hits = hits + 1, name = excluded.name"
+	if sql != want {
+		t.Fatalf("pg sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"a@x.io", "Alice"}) {
+		t.Fatalf("pg args mismatch: %#v", args)
+	}
+
+	sql, args = upsert(NewQB().WithDialect(MySQL)).Build()
+	want = "INSERT INTO users (email, name) VALUES (?, ?) AS excluded ON DUPLICATE KEY UPDATE hits = hits + 1, name = excluded.name"
+	if sql != want {
+		t.Fatalf("mysql sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"a@x.io", "Alice"}) {
+		t.Fatalf("mysql args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().WithDialect(MySQL).Insert("users").Set("email", "a@x.io").
+		MergeKey("email").OnConflictDoNothing().Build()
+	if sql != "INSERT INTO users (email) VALUES (?) ON DUPLICATE KEY UPDATE email = email" {
+		t.Fatalf("mysql do-nothing sql: %s", sql)
+	}
+}
+
+func TestSelectNull(t *testing.T) {
+	sql, _ := NewQB().Select("id").SelectNull("note", "text").From("a").Build()
+	if sql != "SELECT id, NULL::text AS note FROM a" {
+		t.Fatalf("pg sql: %s", sql)
+	}
+
+	sql, _ = NewQB().WithDialect(MySQL).Select("id").SelectNull("note", "text").
+		SelectNull("n", "integer").SelectNull("d", "date").From("a").Build()
+	want := "SELECT id, CAST(NULL AS CHAR) AS note, CAST(NULL AS SIGNED) AS n, CAST(NULL AS DATE) AS d FROM a"
+	if sql != want {
+		t.Fatalf("mysql sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestFromRaw_ArgsPrecedeWhere(t *testing.T) {
+	sql, args := NewQB().Select("g").FromRaw("generate_series(?, ?) AS g", 1, 10).
+		Where("g", GT, 3).Build()
+	if sql != "SELECT g FROM generate_series($1, $2) AS g WHERE g > $3" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 10, 3}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	_, _, err := NewQB().Select("*").FromRaw("generate_series(?, ?) AS g", 1).BuildErr()
+	if err == nil {
+		t.Fatal("expected placeholder count error")
+	}
+}
+
+func TestWhereRange(t *testing.T) {
+	sql, args := NewQB().Select("id").From("users").WhereRange("age", 18, 65).Build()
+	if sql != "SELECT id FROM users WHERE age >= $1 AND age <= $2" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{18, 65}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestBuildOffset(t *testing.T) {
+	b := NewQB()
+	sql, args := b.Select("id").From("t").Where("a", EQ, 1).Where("b", EQ, 2).BuildOffset(3)
+	if sql != "SELECT id FROM t WHERE a = $4 AND b = $5" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 2}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	// the offset does not stick
+	if sql, _ := b.Select("id").From("t").Where("a", EQ, 1).Build(); sql != "SELECT id FROM t WHERE a = $1" {
+		t.Fatalf("offset leaked into next build: %s", sql)
+	}
+}
+
+func TestWhereTupleInSub(t *testing.T) {
+	sub := NewQB().Select("tenant_id", "user_id").From("grants").Where("role", EQ, "admin")
+	sql, args := NewQB().Select("*").From("users").
+		Where("active", EQ, true).
+		WhereTupleInSub([]string{"tenant_id", "id"}, sub).
+		Build()
+	want := "SELECT * FROM users WHERE active = $1 AND (tenant_id, id) IN (SELECT tenant_id, user_id FROM grants WHERE role = $2)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, "admin"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	_, _, err := NewQB().Select("*").From("users").
+		WhereTupleInSub([]string{"tenant_id", "id"}, NewQB().Select("user_id").From("grants")).
+		BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "tuple IN") {
+		t.Fatalf("expected column count error, got: %v", err)
+	}
+}
+
+func TestDisableGuard_Persists(t *testing.T) {
+	b := NewQB().DisableGuard()
+	sql, _ := b.Update("jobs").SetUpdate("state", "queued").Build()
+	if sql != "UPDATE jobs SET state = $1" {
+		t.Fatalf("unexpected first sql: %s", sql)
+	}
+	sql, _ = b.Delete("jobs_archive").Build()
+	if sql != "DELETE FROM jobs_archive" {
+		t.Fatalf("unexpected second sql: %s", sql)
+	}
+
+	sql, _ = b.Delete("jobs").Safe().Build()
+	if !strings.Contains(sql, "WHERE 1=0") {
+		t.Fatalf("Safe should re-guard a single query: %s", sql)
+	}
+}
+
+func TestDistinctOn_OrderByCheck(t *testing.T) {
+	sql, _, err := NewQB().Select("user_id", "created_at", "status").From("events").
+		DistinctOn("user_id").
+		OrderBy("user_id").OrderByDesc("created_at").
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "SELECT DISTINCT ON (user_id) user_id, created_at, status FROM events ORDER BY user_id ASC, created_at DESC"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	_, _, err = NewQB().Select("user_id", "created_at").From("events").
+		DistinctOn("user_id").
+		OrderByDesc("created_at").
+		BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "DISTINCT ON (user_id) requires ORDER BY") {
+		t.Fatalf("expected ORDER BY mismatch error, got: %v", err)
+	}
+
+	_, _, err = NewQB().WithDialect(MySQL).Select("a").From("t").DistinctOn("a").OrderBy("a").BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "only supported by PostgreSQL") {
+		t.Fatalf("expected dialect error, got: %v", err)
+	}
+}
+
+func TestArray_SinglePlaceholder(t *testing.T) {
+	sql, args := NewQB().Insert("posts").Set("id", 1).Set("tags", Array([]string{"a", `b"c`})).Build()
+	if sql != "INSERT INTO posts (id, tags) VALUES ($1, $2)" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+	if len(args) != 2 {
+		t.Fatalf("expected 2 args, got: %#v", args)
+	}
+	arr, ok := args[1].(ArrayValue)
+	if !ok {
+		t.Fatalf("expected ArrayValue to pass through, got %T", args[1])
+	}
+	v, err := arr.Value()
+	if err != nil || v != `{"a","b\"c"}` {
+		t.Fatalf("Value() = %v, %v", v, err)
+	}
+
+	v, _ = Array([][]int{{1, 2}, {3, 4}}).Value()
+	if v != "{{1,2},{3,4}}" {
+		t.Fatalf("nested Value() = %v", v)
+	}
+	if v, _ := Array([]string(nil)).Value(); v != nil {
+		t.Fatalf("nil slice should bind NULL, got %v", v)
+	}
+	if _, err := Array(42).Value(); err == nil {
+		t.Fatal("expected error for non-slice")
+	}
+}
+
+func TestOrderByRandom(t *testing.T) {
+	sql, _ := NewQB().Select("id").From("quotes").OrderByRandom().Limit(3).Build()
+	if sql != "SELECT id FROM quotes ORDER BY RANDOM() LIMIT 3" {
+		t.Fatalf("pg sql: %s", sql)
+	}
+	sql, _ = NewQB().WithDialect(MySQL).Select("id").From("quotes").OrderByRandom().Limit(3).Build()
+	if sql != "SELECT id FROM quotes ORDER BY RAND() LIMIT 3" {
+		t.Fatalf("mysql sql: %s", sql)
+	}
+}
+
+func TestLockClauses(t *testing.T) {
+	sql, _ := NewQB().Select("id").From("jobs j").Where("state", EQ, "ready").
+		Limit(10).ForNoKeyUpdate().Of("j").SkipLocked().Build()
+	if sql != "SELECT id FROM jobs j WHERE state = $1 LIMIT 10 FOR NO KEY UPDATE OF j SKIP LOCKED" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+
+	sql, _ = NewQB().Select("id").From("users").ForKeyShare().NoWait().Build()
+	if sql != "SELECT id FROM users FOR KEY SHARE NOWAIT" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+
+	sql, _ = NewQB().WithDialect(MySQL).Select("id").From("users").ForUpdate().Build()
+	if sql != "SELECT id FROM users FOR UPDATE" {
+		t.Fatalf("unexpected MySQL sql: %s", sql)
+	}
+
+	sql, _, err := NewQB().WithDialect(MySQL).Select("id").From("users").ForNoKeyUpdate().BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "FOR NO KEY UPDATE is not supported") || sql != "" {
+		t.Fatalf("expected MySQL error, got %q, %v", sql, err)
+	}
+	sql, _ = NewQB().WithDialect(MySQL).Select("id").From("users").ForNoKeyUpdate().Build()
+	if sql != "SELECT id FROM users" {
+		t.Fatalf("lock should be dropped on MySQL: %s", sql)
+	}
+
+	sql, _, err = NewQB().WithDialect(Oracle).Select("id").From("jobs j").Where("state", EQ, "ready").
+		ForUpdate().Of("j.id").SkipLocked().BuildErr()
+	if err != nil || sql != "SELECT id FROM jobs j WHERE state = :1 FOR UPDATE OF j.id SKIP LOCKED" {
+		t.Fatalf("unexpected Oracle result: %s, %v", sql, err)
+	}
+	for _, b := range []*QueryBuilder{
+		NewQB().WithDialect(Oracle).Select("id").From("users").ForShare(),
+		NewQB().WithDialect(Oracle).Select("id").From("users").ForNoKeyUpdate(),
+		NewQB().WithDialect(Oracle).Select("id").From("users").ForKeyShare(),
+	} {
+		if _, _, err := b.BuildErr(); err == nil || !strings.Contains(err.Error(), "is not supported") {
+			t.Fatalf("expected Oracle lock error, got %v", err)
+		}
+	}
+}
+
+func TestLimitAll(t *testing.T) {
+	sql, _ := NewQB().Select("id").From("t").OrderBy("id").LimitAll().Offset(20).Build()
+	if sql != "SELECT id FROM t ORDER BY id ASC LIMIT ALL OFFSET 20" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+	sql, _ = NewQB().Select("id").From("t").LimitAll().Limit(5).Build()
+	if sql != "SELECT id FROM t LIMIT 5" {
+		t.Fatalf("Limit should replace LimitAll: %s", sql)
+	}
+	sql, _ = NewQB().WithDialect(SQLite).Select("id").From("t").LimitAll().Build()
+	if sql != "SELECT id FROM t" {
+		t.Fatalf("LIMIT ALL should be PostgreSQL-only: %s", sql)
+	}
+	sql, _ = NewQB().WithDialect(MySQL).Select("id").From("t").LimitAll().Offset(20).Build()
+	if sql != "SELECT id FROM t LIMIT 18446744073709551615 OFFSET 20" {
+		t.Fatalf("MySQL needs a LIMIT before OFFSET: %s", sql)
+	}
+	sql, _ = NewQB().WithDialect(SQLite).Select("id").From("t").Offset(20).Build()
+	if sql != "SELECT id FROM t LIMIT -1 OFFSET 20" {
+		t.Fatalf("SQLite needs a LIMIT before OFFSET: %s", sql)
+	}
+	sql, _ = NewQB().WithDialect(MySQL).Select("id").From("t").Limit(5).Offset(20).Build()
+	if sql != "SELECT id FROM t LIMIT 5 OFFSET 20" {
+		t.Fatalf("explicit Limit should win: %s", sql)
+	}
+}
+
+func TestWith_DataModifyingCTE(t *testing.T) {
+	cutoff := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	moved := NewQB().Delete("events").Where("created_at", LT, cutoff).Returning("*")
+	sql, args, err := NewQB().
+		With("moved", moved).
+		Insert("events_archive").
+		InsertFromSelect(NewQB().Select("*").From("moved").Where("kind", NEQ, "debug")).
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "WITH moved AS (DELETE FROM events WHERE created_at < $1 RETURNING *) " +
+		"INSERT INTO events_archive SELECT * FROM moved WHERE kind != $2"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{cutoff, "debug"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	// the CTE builder is not reset and can render again
+	sql, _ = NewQB().With("moved", moved).Select("id").From("moved").Build()
+	if sql != "WITH moved AS (DELETE FROM events WHERE created_at < $1 RETURNING *) SELECT id FROM moved" {
+		t.Fatalf("unexpected select sql: %s", sql)
+	}
+
+	_, _, err = NewQB().WithDialect(MySQL).With("moved", moved).Select("id").From("moved").BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "requires PostgreSQL") {
+		t.Fatalf("expected dialect error, got: %v", err)
+	}
+}
+
+func TestInsertFromSelect_Columns(t *testing.T) {
+	sql, args := NewQB().Insert("admins").
+		InsertFromSelect(NewQB().Select("id", "email").From("users").Where("role", EQ, "admin"), "user_id", "email").
+		OnConflictDoNothing().
+		Build()
+	want := "INSERT INTO admins (user_id, email) SELECT id, email FROM users WHERE role = $1 ON CONFLICT DO NOTHING"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"admin"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestSelectStruct(t *testing.T) {
+	type base struct {
+		ID int `db:"id"`
+	}
+	type user struct {
+		base
+		Email    string `db:"email"`
+		Password string `db:"-"`
+		Name     string
+		internal int
+	}
+
+	var users []user
+	sql, _ := NewQB().SelectStruct(&users).From("users").Build()
+	if sql != "SELECT id, email, name FROM users" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+
+	b := NewQB().SelectStruct(user{})
+	if got := b.GetColumns(); !reflect.DeepEqual(got, []string{"id", "email", "name"}) {
+		t.Fatalf("columns = %v", got)
+	}
+
+	_, _, err := NewQB().SelectStruct(42).From("users").BuildErr()
+	if err == nil {
+		t.Fatal("expected error for non-struct")
+	}
+}
+
+func TestQualifyWith(t *testing.T) {
+	sql, _ := NewQB().Select("id", "name", "u.email", "COUNT(*)", "created_at AS joined").
+		SelectRaw("lower(name) AS lname").
+		QualifyWith("u").
+		From("users u").Join("orders o", "o.user_id = u.id").
+		Build()
+	want := "SELECT u.id, u.name, u.email, COUNT(*), u.created_at AS joined, lower(name) AS lname FROM users u INNER JOIN orders o ON o.user_id = u.id"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestWhereParenMarkers(t *testing.T) {
+	sql, args := NewQB().Select("id").From("t").
+		WhereOpenParen().Where("a", EQ, 1).OrWhere("b", EQ, 2).WhereCloseParen().
+		Where("c", EQ, 3).
+		Build()
+	if sql != "SELECT id FROM t WHERE (a = $1 OR b = $2) AND c = $3" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 2, 3}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().Select("id").From("t").
+		Where("c", EQ, 3).
+		OrWhereOpenParen().Where("a", EQ, 1).Where("b", EQ, 2).WhereCloseParen().
+		Build()
+	if sql != "SELECT id FROM t WHERE c = $1 OR (a = $2 AND b = $3)" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+
+	_, _, err := NewQB().Select("id").From("t").WhereOpenParen().Where("a", EQ, 1).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "unbalanced") {
+		t.Fatalf("expected unbalanced error, got: %v", err)
+	}
+}
+
+func TestUpdateManyFromValues(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "a"},
+		{"id": 2, "name": "b"},
+	}
+	sql, args, err := NewQB().UpdateManyFromValues("users", "id", rows).
+		Where("tenant_id", EQ, 7).
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "UPDATE users SET name = v.name FROM (VALUES ($1, $2), ($3, $4)) AS v(id, name) WHERE users.id = v.id AND tenant_id = $5"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "a", 2, "b", 7}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	if got := NewQB().UpdateManyFromValues("users", "id", rows).UpdatedColumns(); !reflect.DeepEqual(got, []string{"name"}) {
+		t.Fatalf("UpdatedColumns = %v", got)
+	}
+
+	sql, _ = NewQB().WithQuoting(true).UpdateManyFromValues("users u", "id", rows).Build()
+	want = `UPDATE "users" "u" SET "name" = "v"."name" FROM (VALUES ($1, $2), ($3, $4)) AS "v"("id", "name") WHERE "u"."id" = "v"."id"`
+	if sql != want {
+		t.Fatalf("quoted sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	_, _, err = NewQB().UpdateManyFromValues("users", "uid", rows).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), `key column "uid"`) {
+		t.Fatalf("expected missing key error, got: %v", err)
+	}
+
+	_, _, err = NewQB().WithDialect(SQLite).UpdateManyFromValues("users", "id", rows).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "not supported by SQLite") {
+		t.Fatalf("expected SQLite error, got: %v", err)
+	}
+}
+
+func TestCountQuery_DistinctPrimaryKeyOverJoin(t *testing.T) {
+	base := NewQB().WithPrimaryKey("id").
+		Select("u.id", "u.name", "o.total").
+		From("users u").
+		LeftJoin("orders o", "o.user_id = u.id").
+		Where("u.active", EQ, true).
+		OrderBy("u.id").
+		Limit(10)
+
+	sql, args := base.CountQuery().Build()
+	if sql != "SELECT COUNT(DISTINCT u.id) FROM users u LEFT JOIN orders o ON o.user_id = u.id WHERE u.active = $1" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().WithPrimaryKey("tenant_id", "id").
+		Select("*").From("users").Join("orders o", "o.user_id = users.id").
+		CountQuery().Build()
+	want := "SELECT COUNT(*) FROM (SELECT DISTINCT users.tenant_id, users.id FROM users INNER JOIN orders o ON o.user_id = users.id) AS qb_count"
+	if sql != want {
+		t.Fatalf("composite sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	// without a registered key a join keeps COUNT(*)
+	sql, _ = NewQB().Select("*").From("users u").Join("orders o", "o.user_id = u.id").CountQuery().Build()
+	if sql != "SELECT COUNT(*) FROM users u INNER JOIN orders o ON o.user_id = u.id" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+}
+
+func TestRenderWhere_AtOffset(t *testing.T) {
+	b := NewQB().Select("id").From("users").Where("status", EQ, "active").WhereIn("role", []string{"a", "b"})
+	frag, args, next := b.RenderWhere(2)
+	if frag != "status = $2 AND role IN ($3, $4)" {
+		t.Fatalf("unexpected fragment: %s", frag)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"active", "a", "b"}) || next != 5 {
+		t.Fatalf("args/next mismatch: %#v, %d", args, next)
+	}
+
+	// qb is untouched and still builds from $1
+	if sql, _ := b.Build(); sql != "SELECT id FROM users WHERE status = $1 AND role IN ($2, $3)" {
+		t.Fatalf("builder was modified: %s", sql)
+	}
+}
+
+func TestSelectExists_Correlated(t *testing.T) {
+	orders := NewQB().SelectRaw("1").From("orders o").
+		WhereColumn("o.user_id", EQ, "u.id").
+		Where("o.status", EQ, "paid")
+	sql, args := NewQB().Select("id").SelectExists(orders, "has_orders").
+		From("users u").
+		Where("u.active", EQ, true).
+		Build()
+	want := "SELECT id, EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id AND o.status = $1) AS has_orders FROM users u WHERE u.active = $2"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"paid", true}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestOnConflictUpdate_Subset(t *testing.T) {
+	q := NewQB().
+		Insert("users").
+		Values(map[string]any{"id": 1, "name": "A", "email": "a@x", "updated_at": "now"}).
+		OnConflict("id").
+		OnConflictUpdate("updated_at", "name")
+	sql, args, err := q.BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "INSERT INTO users (email, id, name, updated_at) VALUES ($1, $2, $3, $4) " +
+		"ON CONFLICT (id) DO UPDATE SET name = excluded.name, updated_at = excluded.updated_at"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 4 {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	_, _, err = NewQB().
+		Insert("users").
+		Values(map[string]any{"id": 1, "name": "A"}).
+		OnConflict("id").
+		OnConflictUpdate("email").
+		BuildErr()
+	if err == nil || !strings.Contains(err.Error(), `"email"`) {
+		t.Fatalf("expected error for non-inserted column, got %v", err)
+	}
+}
+
+func TestOnConflictUpdate_BeforeValues(t *testing.T) {
+	q := NewQB().
+		Insert("users").
+		OnConflict("id").
+		OnConflictUpdate("name", "email").
+		Values(map[string]any{"id": 1, "name": "A"})
+	if _, _, err := q.Clone().BuildErr(); err == nil || !strings.Contains(err.Error(), `"email"`) {
+		t.Fatalf("expected error for non-inserted column only, got %v", err)
+	} else if strings.Contains(err.Error(), `"name"`) {
+		t.Fatalf("inserted column reported: %v", err)
+	}
+
+	sql, _ := q.Build()
+	want := "INSERT INTO users (id, name) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET name = excluded.name"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestPaginateWindow(t *testing.T) {
+	base := NewQB().Select().From("users").Where("active", EQ, true).OrderBy("name").Limit(10)
+
+	sql, args := base.PaginateWindow([]OrderBy{{Column: "created_at", Desc: true}, {Column: "id"}}, 3, 20).Build()
+	want := "SELECT * FROM (SELECT *, ROW_NUMBER() OVER (ORDER BY created_at DESC, id ASC) AS rn " +
+		"FROM users WHERE active = $1) AS t WHERE rn BETWEEN $2 AND $3 ORDER BY rn ASC"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, 41, 60}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	if sql, _ := base.Build(); sql != "SELECT * FROM users WHERE active = $1 ORDER BY name ASC LIMIT 10" {
+		t.Fatalf("base builder changed: %s", sql)
+	}
+}
+
+func TestSelectTotalCount(t *testing.T) {
+	sql, args := NewQB().
+		Select("id", "name").
+		SelectTotalCount("").
+		From("users").
+		Where("active", EQ, true).
+		OrderBy("id").
+		Paginate(2, 10).
+		Build()
+
+	want := "SELECT id, name, COUNT(*) OVER() AS total FROM users WHERE active = $1 ORDER BY id ASC LIMIT 10 OFFSET 10"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().WithQuoting(true).Select("id").SelectTotalCount("n").From("users").Build()
+	if sql != `SELECT "id", COUNT(*) OVER() AS n FROM "users"` {
+		t.Fatalf("raw window column should not be quoted: %s", sql)
+	}
+}
+
+func TestSetUpdateIf(t *testing.T) {
+	name, email := "Ann", ""
+	sql, args, err := NewQB().
+		Update("users").
+		SetUpdateIf(name != "", "name", name).
+		SetUpdateIf(email != "", "email", email).
+		SetUpdateIf(true, "updated_at", "now").
+		Where("id", EQ, 7).
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "UPDATE users SET name = $1, updated_at = $2 WHERE id = $3"; sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"Ann", "now", 7}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	_, _, err = NewQB().
+		Update("users").
+		SetUpdateIf(false, "name", name).
+		SetUpdateIf(false, "email", email).
+		Where("id", EQ, 7).
+		BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "no SET assignments") {
+		t.Fatalf("expected empty SET error, got: %v", err)
+	}
+}
+
+func TestDistinctOperators_PerDialect(t *testing.T) {
+	build := func(d Dialect) (string, []interface{}) {
+		return NewQB().
+			WithDialect(d).
+			Select("id").
+			From("users").
+			Where("manager_id", DISTINCT, nil).
+			Where("team_id", NOTDISTINCT, 3).
+			WhereColumn("a.x", DISTINCT, "b.x").
+			GroupBy("id").
+			Having("region", DISTINCT, "eu").
+			Build()
+	}
+
+	sql, args := build(Postgres)
+	want := "SELECT id FROM users WHERE manager_id IS DISTINCT FROM $1 AND team_id IS NOT DISTINCT FROM $2 " +
+		"AND a.x IS DISTINCT FROM b.x GROUP BY id HAVING region IS DISTINCT FROM $3"
+	if sql != want {
+		t.Fatalf("postgres sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{nil, 3, "eu"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = build(MySQL)
+	want = "SELECT id FROM users WHERE NOT (manager_id <=> ?) AND team_id <=> ? " +
+		"AND NOT (a.x <=> b.x) GROUP BY id HAVING NOT (region <=> ?)"
+	if sql != want {
+		t.Fatalf("mysql sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	sql, _ = build(SQLite)
+	want = "SELECT id FROM users WHERE manager_id IS NOT ? AND team_id IS ? " +
+		"AND a.x IS NOT b.x GROUP BY id HAVING region IS NOT ?"
+	if sql != want {
+		t.Fatalf("sqlite sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestWithMissingValue_FillsMissingColumns(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "A", "role": "admin"},
+		{"id": 2, "name": "B"},
+	}
+	build := func(mode MissingValue) (string, []interface{}, error) {
+		return NewQB().WithMissingValue(mode).Insert("users").ValuesBatch(rows).BuildErr()
+	}
+
+	sql, args, err := build(NullValue)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "INSERT INTO users (id, name, role) VALUES ($1, $2, $3), ($4, $5, NULL)"; sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "A", "admin", 2, "B"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _, err = build(DefaultKeyword)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "INSERT INTO users (id, name, role) VALUES ($1, $2, $3), ($4, $5, DEFAULT)"; sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	if _, _, err := build(MissingReported); err == nil {
+		t.Fatal("expected the default mode to report the differing row")
+	}
+	_, _, err = NewQB().WithDialect(SQLite).WithMissingValue(DefaultKeyword).Insert("users").ValuesBatch(rows).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "SQLite") {
+		t.Fatalf("expected SQLite DEFAULT error, got: %v", err)
+	}
+}
+
+func TestConfigure_SurvivesBuildResetCycles(t *testing.T) {
+	q := NewQB().WithDialect(MySQL).Configure(
+		func(b *QueryBuilder) { b.DisableGuard() },
+		func(b *QueryBuilder) { b.Scope("tenant_id", EQ, 7) },
+	)
+
+	for i := 0; i < 3; i++ {
+		sql, args := q.Select("id").From("users").Where("active", EQ, true).Build()
+		if want := "SELECT id FROM users WHERE active = ? AND tenant_id = ?"; sql != want {
+			t.Fatalf("cycle %d select mismatch:\n got: %s\nwant: %s", i, sql, want)
+		}
+		if !reflect.DeepEqual(args, []interface{}{true, 7}) {
+			t.Fatalf("cycle %d args mismatch: %#v", i, args)
+		}
+
+		sql, _ = q.Delete("sessions").Build()
+		if want := "DELETE FROM sessions WHERE tenant_id = ?"; sql != want {
+			t.Fatalf("cycle %d delete mismatch:\n got: %s\nwant: %s", i, sql, want)
+		}
+		q.Reset()
+	}
+
+	// builders derived from the baseline do not leak it into their wrappers
+	sql, _ := q.Select("id").From("users").Wrap("t").Build()
+	if want := "SELECT * FROM (SELECT id FROM users WHERE tenant_id = ?) AS t"; sql != want {
+		t.Fatalf("wrap mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestWhereAnyOf(t *testing.T) {
+	sql, args := NewQB().
+		Select("id").
+		From("users").
+		Where("active", EQ, true).
+		WhereAnyOf(
+			map[string]interface{}{"b": 2, "a": 1},
+			map[string]interface{}{"c": 4, "a": 3},
+			map[string]interface{}{},
+			map[string]interface{}{"deleted_at": nil},
+		).
+		Build()
+
+	want := "SELECT id FROM users WHERE active = $1 AND ((a = $2 AND b = $3) OR (a = $4 AND c = $5) OR (deleted_at IS NULL))"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, 1, 2, 3, 4}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	if sql, _ := NewQB().Select("id").From("users").WhereAnyOf().Build(); sql != "SELECT id FROM users" {
+		t.Fatalf("expected no WHERE without filter sets, got: %s", sql)
+	}
+}
+
+func TestUnionAll_OuterOrderByLimit(t *testing.T) {
+	comments := NewQB().Select("id", "created_at").From("comments").Where("author_id", EQ, 7)
+	q := NewQB().
+		Select("id", "created_at").
+		From("posts").
+		Where("author_id", EQ, 7).
+		OrderByDesc("created_at").
+		UnionAll(comments).
+		Limit(10)
+
+	sql, args, err := q.BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "(SELECT id, created_at FROM posts WHERE author_id = $1) UNION ALL " +
+		"(SELECT id, created_at FROM comments WHERE author_id = $2) ORDER BY created_at DESC LIMIT 10"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{7, 7}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().WithDialect(SQLite).Select("id").From("a").Union(NewQB().Select("id").From("b")).OrderBy("id").Build()
+	if want := "SELECT id FROM a UNION SELECT id FROM b ORDER BY id ASC"; sql != want {
+		t.Fatalf("sqlite sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	_, _, err = NewQB().Select("id", "name").From("a").Union(NewQB().Select("id").From("b")).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "selects 1 columns, want 2") {
+		t.Fatalf("expected column count error, got: %v", err)
+	}
+}
+
+func TestSelectAllowed(t *testing.T) {
+	allowed := map[string]string{"id": "u.id", "name": "u.full_name AS name", "email": "u.email"}
+
+	q := NewQB().SelectAllowed([]string{"name", " id", "name"}, allowed).From("users u")
+	sql, _, err := q.BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "SELECT u.full_name AS name, u.id FROM users u"; sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	q = NewQB().Delete("users").SelectAllowed([]string{"id", "password"}, allowed).From("users u")
+	if _, _, err := q.Clone().BuildErr(); err == nil || !strings.Contains(err.Error(), `"password"`) {
+		t.Fatalf("expected disallowed field error, got: %v", err)
+	}
+	sql, _ = q.Build()
+	if want := "SELECT u.id FROM users u"; sql != want {
+		t.Fatalf("disallowed field should be dropped:\n got: %s\nwant: %s", sql, want)
+	}
+
+	// nothing requested (or nothing left) selects every allowed column, never *
+	sql, _ = NewQB().SelectAllowed(nil, allowed).From("users u").Build()
+	if want := "SELECT u.email, u.id, u.full_name AS name FROM users u"; sql != want {
+		t.Fatalf("default columns mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	sql, _ = NewQB().SelectAllowed([]string{"password"}, allowed).From("users u").Build()
+	if strings.Contains(sql, "*") || strings.Contains(sql, "password") {
+		t.Fatalf("fallback must only select allowed columns: %s", sql)
+	}
+}
+
+func TestWhereIn_NilElements(t *testing.T) {
+	a := "a"
+	for _, style := range []PlaceholderStyle{DollarN, QuestionMark} {
+		q := NewQB().WithPlaceholders(style).Select("id").From("t").
+			WhereIn("s", []*string{&a, nil}).
+			WhereIn("v", []interface{}{1, (*int)(nil)}).
+			WhereIn("b", []bool{true, false})
+		if _, _, err := q.Clone().BuildErr(); err == nil || !strings.Contains(err.Error(), "WhereIn list for s contains NULL") {
+			t.Fatalf("expected NULL-in-IN error, got: %v", err)
+		}
+
+		_, args := q.Build()
+		want := []interface{}{&a, nil, 1, nil, true, false}
+		if !reflect.DeepEqual(args, want) {
+			t.Fatalf("style %d args mismatch:\n got: %#v\nwant: %#v", style, args, want)
+		}
+		if args[1] != nil || args[3] != nil {
+			t.Fatalf("style %d: nil elements must bind untyped nil, got %#v and %#v", style, args[1], args[3])
+		}
+	}
+}
+
+func TestWhereNot(t *testing.T) {
+	sql, args := NewQB().
+		Select("id").
+		From("users").
+		WhereNot("status", EQ, "banned").
+		WhereNot("email", LIKE, "%@test.local").
+		WhereNot("deleted_at", NOTNULL, nil).
+		Build()
+
+	want := "SELECT id FROM users WHERE NOT (status = $1) AND NOT (email LIKE $2) AND NOT (deleted_at IS NOT NULL)"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"banned", "%@test.local"}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+}
+
+func TestOracle_FetchPagination(t *testing.T) {
+	sql, args := NewQB().
+		WithDialect(Oracle).
+		Select("id", "name").
+		From("users").
+		Where("active", EQ, 1).
+		OrderBy("id").
+		Limit(10).
+		Offset(5).
+		Build()
+
+	want := "SELECT id, name FROM users WHERE active = :1 ORDER BY id ASC OFFSET 5 ROWS FETCH NEXT 10 ROWS ONLY"
+	if sql != want {
+		t.Fatalf("sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _ = NewQB().WithDialect(Oracle).Select("id").From("users").OrderBy("id").Limit(3).Build()
+	if want := "SELECT id FROM users ORDER BY id ASC FETCH FIRST 3 ROWS ONLY"; sql != want {
+		t.Fatalf("limit-only mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	sql, _ = NewQB().WithDialect(Oracle).Select("id").From("users").OrderBy("id").Offset(20).Build()
+	if want := "SELECT id FROM users ORDER BY id ASC OFFSET 20 ROWS"; sql != want {
+		t.Fatalf("offset-only mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	if got := NewQB().WithDialect(Oracle).Select("id").From("users").Where("name", EQ, "O'Neil").DebugSQL(); got != "SELECT id FROM users WHERE name = 'O''Neil'" {
+		t.Fatalf("DebugSQL mismatch: %s", got)
+	}
+	if got, next := Renumber("a = ? AND b = ?", 3, ColonN); got != "a = :3 AND b = :4" || next != 5 {
+		t.Fatalf("Renumber mismatch: %s, %d", got, next)
+	}
+}
+
+func TestOracle_PostgresOnlyConstructs(t *testing.T) {
+	ora := func() *QueryBuilder { return NewQB().WithDialect(Oracle) }
+	cases := []struct {
+		name string
+		b    *QueryBuilder
+		want string
+	}{
+		{"random order", ora().Select("id").From("users").OrderByRandom(), "SELECT id FROM users ORDER BY DBMS_RANDOM.VALUE"},
+		{"cast", ora().Select("id").From("users").WhereCast("id", EQ, "x", "VARCHAR2(36)"), "SELECT id FROM users WHERE id = CAST(:1 AS VARCHAR2(36))"},
+		{"distinct", ora().Select("id").From("users").Where("a", DISTINCT, 1), "SELECT id FROM users WHERE DECODE(a, :1, 0, 1) = 1"},
+		{"not distinct", ora().Select("id").From("users").WhereNullSafeEq("a", 1), "SELECT id FROM users WHERE DECODE(a, :1, 0, 1) = 0"},
+		{"array length", ora().Select("id").From("posts").WhereArrayLen("tags", GT, 2), "SELECT id FROM posts WHERE JSON_VALUE(tags, '$.size()' RETURNING NUMBER) > :1"},
+		{"date", ora().Select("id").From("orders").WhereDate("created_at", GTE, time.Date(2024, 3, 9, 15, 0, 0, 0, time.UTC)),
+			"SELECT id FROM orders WHERE TRUNC(created_at) >= TO_DATE(:1, 'YYYY-MM-DD')"},
+		{"subquery", ora().Select("*").FromSubquery(ora().Select("id").From("users"), "u"), "SELECT * FROM (SELECT id FROM users) u"},
+		{"wrap", ora().Select("id").From("users").Wrap("w"), "SELECT * FROM (SELECT id FROM users) w"},
+		{"count", ora().Select("status").From("users").GroupBy("status").CountQuery(), "SELECT COUNT(*) FROM (SELECT status FROM users GROUP BY status) qb_count"},
+		{"paginate window", ora().Select("id").From("users").PaginateWindow([]OrderBy{{Column: "id"}}, 2, 10),
+			"SELECT * FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY id ASC) AS rn FROM users) t WHERE rn BETWEEN :1 AND :2 ORDER BY rn ASC"},
+		{"delete limited", ora().Delete("jobs").Where("done", EQ, 1).DeleteLimited(100),
+			"DELETE FROM jobs WHERE id IN (SELECT id FROM jobs WHERE done = :1 FETCH FIRST :2 ROWS ONLY)"},
+	}
+	for _, tc := range cases {
+		if sql, _ := tc.b.Build(); sql != tc.want {
+			t.Fatalf("%s mismatch:\n got: %s\nwant: %s", tc.name, sql, tc.want)
+		}
+	}
+
+	reported := []struct {
+		name string
+		b    *QueryBuilder
+		err  string
+	}{
+		{"returning", ora().Delete("users").Where("id", EQ, 1).Returning("id"), "RETURNING is not supported on Oracle"},
+		{"on conflict", ora().Insert("users").Set("id", 1).OnConflict("id").OnConflictDoNothing(), "ON CONFLICT is not supported on Oracle"},
+		{"default values", ora().Insert("events"), "DEFAULT VALUES"},
+		{"values table", ora().Select("*").FromValues("v", []string{"id"}, [][]interface{}{{1}}), "VALUES lists as tables"},
+		{"update from values", ora().UpdateManyFromValues("users", "id", []map[string]interface{}{{"id": 1, "name": "a"}}), "UpdateManyFromValues is not supported on Oracle"},
+		{"multi-row insert", ora().Insert("users").ValuesBatch([]map[string]interface{}{{"id": 1}, {"id": 2}}), "multi-row VALUES"},
+	}
+	for _, tc := range reported {
+		_, _, err := tc.b.Clone().BuildErr()
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected %q error, got: %v", tc.name, tc.err, err)
+		}
+		if sql, _ := tc.b.Build(); strings.Contains(sql, "RETURNING") || strings.Contains(sql, "ON CONFLICT") {
+			t.Fatalf("%s: unsupported clause rendered: %s", tc.name, sql)
+		}
+	}
+}
+
+func TestSQLServer_TopAndOffsetFetch(t *testing.T) {
+	sql, args := NewQB().
+		WithDialect(SQLServer).
+		Select("id", "name").
+		Distinct().
+		From("users").
+		Where("active", EQ, true).
+		Limit(5).
+		Build()
+
+	want := "SELECT DISTINCT TOP (5) id, name FROM users WHERE active = @p1"
+	if sql != want {
+		t.Fatalf("top sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	sql, _, err := NewQB().
+		WithDialect(SQLServer).
+		Select("id").
+		From("users").
+		Where("active", EQ, true).
+		OrderByDesc("created_at").
+		Paginate(3, 10).
+		BuildErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = "SELECT id FROM users WHERE active = @p1 ORDER BY created_at DESC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"
+	if sql != want {
+		t.Fatalf("offset/fetch sql mismatch:\n got: %s\nwant: %s", sql, want)
+	}
+
+	_, _, err = NewQB().WithDialect(SQLServer).Select("id").From("users").Limit(10).Offset(10).BuildErr()
+	if err == nil || !strings.Contains(err.Error(), "requires ORDER BY") {
+		t.Fatalf("expected ORDER BY error, got: %v", err)
+	}
+}
+
+func TestSQLServer_PostgresOnlyConstructs(t *testing.T) {
+	mss := func() *QueryBuilder { return NewQB().WithDialect(SQLServer) }
+	cases := []struct {
+		name string
+		b    *QueryBuilder
+		want string
+	}{
+		{"random order", mss().Select("id").From("users").OrderByRandom().Limit(5), "SELECT TOP (5) id FROM users ORDER BY NEWID()"},
+		{"nulls order", mss().Select("id").From("users").OrderByDynamic("name:asc:nullslast", map[string]string{"name": "name"}), "SELECT id FROM users ORDER BY name ASC"},
+		{"cast", mss().Select("id").From("users").WhereCast("id", EQ, "x", "UNIQUEIDENTIFIER"), "SELECT id FROM users WHERE id = CAST(@p1 AS UNIQUEIDENTIFIER)"},
+		{"distinct", mss().Select("id").From("users").Where("a", DISTINCT, 1), "SELECT id FROM users WHERE NOT EXISTS (SELECT a INTERSECT SELECT @p1)"},
+		{"not distinct", mss().Select("id").From("users").WhereNullSafeEq("a", 1), "SELECT id FROM users WHERE EXISTS (SELECT a INTERSECT SELECT @p1)"},
+		{"array length", mss().Select("id").From("posts").WhereArrayLen("tags", GT, 2), "SELECT id FROM posts WHERE (SELECT COUNT(*) FROM OPENJSON(tags)) > @p1"},
+		{"date parts", mss().Select("id").From("orders").WhereYear("created_at", EQ, 2024).WhereDate("created_at", EQ, time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)),
+			"SELECT id FROM orders WHERE YEAR(created_at) = @p1 AND CAST(created_at AS DATE) = @p2"},
+		{"delete limited", mss().Delete("jobs").Where("done", EQ, 1).DeleteLimited(100), "DELETE TOP (@p1) FROM jobs WHERE done = @p2"},
+		{"ordered delete limited", mss().Delete("jobs").Where("done", EQ, 1).OrderBy("id").DeleteLimited(100),
+			"DELETE FROM jobs WHERE id IN (SELECT TOP (@p1) id FROM jobs WHERE done = @p2 ORDER BY id ASC)"},
+		{"guarded delete limited", mss().Delete("jobs").DeleteLimited(100), "DELETE FROM jobs" + guardWhere},
+	}
+	for _, tc := range cases {
+		if sql, _ := tc.b.Build(); sql != tc.want {
+			t.Fatalf("%s mismatch:\n got: %s\nwant: %s", tc.name, sql, tc.want)
+		}
+	}
+
+	_, args := mss().Delete("jobs").Where("done", EQ, 1).DeleteLimited(100).Build()
+	if !reflect.DeepEqual(args, []interface{}{100, 1}) {
+		t.Fatalf("args mismatch: %#v", args)
+	}
+
+	reported := []struct {
+		name string
+		b    *QueryBuilder
+		err  string
+	}{
+		{"returning", mss().Delete("users").Where("id", EQ, 1).Returning("id"), "RETURNING is not supported on SQL Server"},
+		{"on conflict", mss().Insert("users").Set("id", 1).OnConflict("id").OnConflictDoNothing(), "ON CONFLICT is not supported on SQL Server"},
+	}
+	for _, tc := range reported {
+		_, _, err := tc.b.Clone().BuildErr()
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected %q error, got: %v", tc.name, tc.err, err)
+		}
+		if sql, _ := tc.b.Build(); strings.Contains(sql, "RETURNING") || strings.Contains(sql, "ON CONFLICT") {
+			t.Fatalf("%s: unsupported clause rendered: %s", tc.name, sql)
+		}
+	}
+}
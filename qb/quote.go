@@ -0,0 +1,212 @@
+package qb
+
+import "strings"
+
+// WithQuoting enables or disables identifier quoting. When enabled, table and
+// column names are quoted at render time ("users"."id" for PostgreSQL,
+// `users`.`id` for MySQL). Quoting is purely syntactic: pass
+// expressions such as COUNT(*) through the *Raw variants (e.g. SelectRaw) so
+// they are left untouched. A sub-builder without its own WithQuoting call
+// inherits quoting from the statement it is embedded in. It returns qb for
+// chaining.
+func (qb *QueryBuilder) WithQuoting(enabled bool) *QueryBuilder {
+	qb.QuoteIdents = enabled
+	qb.quoteSet = true
+	return qb
+}
+
+// WithTablePrefix prepends prefix to every table name rendered for FROM,
+// JOIN, INSERT, UPDATE and DELETE, e.g. for shared-schema multitenancy:
+// From("users u") renders "t123_users u". A schema qualifier is kept in front
+// ("public.users" ⇒ "public.t123_users") and quoting applies afterwards.
+func (qb *QueryBuilder) WithTablePrefix(prefix string) *QueryBuilder {
+	qb.TablePrefix = prefix
+	return qb
+}
+
+// SelectRaw appends raw expressions to the SELECT list (e.g.
+// "EXTRACT(year FROM created_at) AS y"). Unlike Select it does not replace
+// previously selected columns, and the quoting pass never touches them.
+func (qb *QueryBuilder) SelectRaw(exprs ...string) *QueryBuilder {
+	qb.startStatement(SELECT)
+	qb.QueryType = SELECT
+	if qb.RawColumns == nil {
+		qb.RawColumns = make(map[string]bool, len(exprs))
+	}
+	for _, e := range exprs {
+		qb.RawColumns[e] = true
+	}
+	qb.Columns = append(qb.Columns, exprs...)
+	return qb
+}
+
+// SelectExpr appends a raw expression column "expr AS alias" whose '?'
+// markers are bound to args, e.g. SelectExpr("price * ?", "discounted", 0.9)
+// renders "price * $1 AS discounted". Column args are bound before those of
+// WHERE and later clauses, in column order. An empty alias omits AS.
+func (qb *QueryBuilder) SelectExpr(expr, alias string, args ...interface{}) *QueryBuilder {
+	entry := expr
+	if alias != "" {
+		entry += " AS " + alias
+	}
+	if n := countRawPlaceholders(expr); n != len(args) {
+		qb.addErr("select expression %q has %d placeholders but %d args", expr, n, len(args))
+	}
+	qb.SelectRaw(entry)
+	if len(args) > 0 {
+		if qb.ColumnArgs == nil {
+			qb.ColumnArgs = make(map[string][]interface{})
+		}
+		qb.ColumnArgs[entry] = args
+	}
+	return qb
+}
+
+// SelectNull appends a typed NULL column "NULL::castType AS alias", e.g. to
+// pad one side of a UNION to the other's shape. MySQL gets
+// CAST(NULL AS type), with common PostgreSQL type names mapped to MySQL's
+// cast targets (text ⇒ CHAR, integer ⇒ SIGNED, ...), and SQLite
+// CAST(NULL AS castType). The dialect is read when called, so configure it
+// first.
+func (qb *QueryBuilder) SelectNull(alias, castType string) *QueryBuilder {
+	var expr string
+	switch qb.dialect() {
+	case MySQL:
+		t, ok := mysqlCastTypes[strings.ToLower(castType)]
+		if !ok {
+			t = strings.ToUpper(castType)
+		}
+		expr = "CAST(NULL AS " + t + ")"
+	case SQLite, Oracle, SQLServer:
+		expr = "CAST(NULL AS " + castType + ")"
+	default:
+		expr = "NULL" + castSuffix(castType)
+	}
+	return qb.SelectRaw(expr + " AS " + alias)
+}
+
+// mysqlCastTypes maps PostgreSQL type names to MySQL CAST targets.
+var mysqlCastTypes = map[string]string{
+	"text":              "CHAR",
+	"varchar":           "CHAR",
+	"character varying": "CHAR",
+	"uuid":              "CHAR",
+	"int":               "SIGNED",
+	"integer":           "SIGNED",
+	"smallint":          "SIGNED",
+	"bigint":            "SIGNED",
+	"numeric":           "DECIMAL",
+	"timestamp":         "DATETIME",
+	"timestamptz":       "DATETIME",
+	"jsonb":             "JSON",
+}
+
+// SelectExists appends the boolean column "EXISTS (<sub>) AS alias", e.g. a
+// correlated has_orders flag. sub's parameters are bound in column order,
+// before those of WHERE; sub itself is not reset.
+func (qb *QueryBuilder) SelectExists(sub *QueryBuilder, alias string) *QueryBuilder {
+	return qb.SelectExpr("EXISTS ?", alias, sub)
+}
+
+// SelectTotalCount appends "COUNT(*) OVER() AS alias" (alias defaults to
+// total), so with Limit/Offset each returned row also carries the count of
+// all matching rows, saving the second query of Page. Every supported
+// dialect has window functions: PostgreSQL, MySQL 8+ and SQLite 3.25+.
+// An empty page returns no rows and therefore no count.
+func (qb *QueryBuilder) SelectTotalCount(alias string) *QueryBuilder {
+	if alias == "" {
+		alias = "total"
+	}
+	return qb.SelectRaw("COUNT(*) OVER() AS " + alias)
+}
+
+// quoteChar returns the identifier quote for the effective dialect.
+func (qb *QueryBuilder) quoteChar() string {
+	if qb.dialect() == MySQL {
+		return "`"
+	}
+	return `"`
+}
+
+// isWildcard reports whether a select entry is "*" or a qualified wildcard
+// such as "u.*" (or its quoted form "u".*).
+func isWildcard(s string) bool {
+	return s == "*" || strings.HasSuffix(s, ".*")
+}
+
+// ident renders an identifier reference, quoting it when quoting is enabled.
+// It understands "tbl.col", "*", "tbl.*", "col AS alias" and "table alias";
+// in "tbl.*" only the qualifier is quoted ("tbl".*).
+func (qb *QueryBuilder) ident(s string) string {
+	if !qb.QuoteIdents {
+		return s
+	}
+
+	fields := strings.Fields(s)
+	switch {
+	case len(fields) == 3 && strings.EqualFold(fields[1], "AS"):
+		return qb.quotePath(fields[0]) + " AS " + qb.quotePart(fields[2])
+	case len(fields) == 2:
+		return qb.quotePath(fields[0]) + " " + qb.quotePart(fields[1])
+	default:
+		return qb.quotePath(s)
+	}
+}
+
+// table renders a table reference ("[schema.]name [alias]"), applying the
+// table prefix and quoting.
+func (qb *QueryBuilder) table(s string) string {
+	if qb.TablePrefix != "" {
+		fields := strings.Fields(s)
+		if len(fields) > 0 {
+			name := fields[0]
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[:i+1] + qb.TablePrefix + name[i+1:]
+			} else {
+				name = qb.TablePrefix + name
+			}
+			fields[0] = name
+			s = strings.Join(fields, " ")
+		}
+	}
+	return qb.ident(s)
+}
+
+// column renders a SELECT list entry; raw expressions are returned verbatim.
+func (qb *QueryBuilder) column(s string) string {
+	if qb.RawColumns[s] {
+		return s
+	}
+	return qb.ident(s)
+}
+
+// identList renders a comma-separated list of identifiers.
+func (qb *QueryBuilder) identList(names []string) string {
+	if !qb.QuoteIdents {
+		return strings.Join(names, ", ")
+	}
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = qb.ident(n)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// quotePath quotes each dot-separated segment of a qualified name.
+func (qb *QueryBuilder) quotePath(s string) string {
+	segs := strings.Split(s, ".")
+	for i, seg := range segs {
+		segs[i] = qb.quotePart(seg)
+	}
+	return strings.Join(segs, ".")
+}
+
+// quotePart quotes a single name segment; "*" and already-quoted segments are
+// kept as-is, embedded quote characters are doubled.
+func (qb *QueryBuilder) quotePart(s string) string {
+	q := qb.quoteChar()
+	if s == "*" || (len(s) >= 2 && strings.HasPrefix(s, q) && strings.HasSuffix(s, q)) {
+		return s
+	}
+	return q + strings.ReplaceAll(s, q, q+q) + q
+}
@@ -0,0 +1,119 @@
+package qb
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Renumber rewrites the '?' markers of a SQL fragment for style: DollarN
+// numbers them $startIndex, $startIndex+1, ... (ColonN and AtPN likewise as
+// :n and @pn);
+// QuestionMark keeps them. It
+// returns the rewritten SQL and the next free index, so fragments can be
+// chained: s2, next := Renumber(frag2, next, DollarN).
+// Markers inside string literals ('why?') and quoted identifiers ("a?",
+// `a?`) are left untouched, doubled quotes included, and "??" is an escaped
+// literal '?' (kept doubled under QuestionMark, where drivers expect that).
+func Renumber(sql string, startIndex int, style PlaceholderStyle) (string, int) {
+	var b strings.Builder
+	b.Grow(len(sql) + 8)
+	next := startIndex
+	prefix := style.numberPrefix()
+	scanMarkers(sql, b.WriteString, func() {
+		if prefix != "" {
+			b.WriteString(prefix)
+			b.WriteString(strconv.Itoa(next))
+		} else {
+			b.WriteByte('?')
+		}
+		next++
+	}, func() {
+		if prefix != "" {
+			b.WriteByte('?')
+		} else {
+			b.WriteString("??")
+		}
+	})
+	return b.String(), next
+}
+
+// scanMarkers walks sql, passing plain text (quoted sections included) to
+// text, calling marker for each '?' placeholder and escaped for each "??".
+func scanMarkers(sql string, text func(string) (int, error), marker, escaped func()) {
+	start := 0
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; c {
+		case '\'', '"', '`':
+			// skip to the closing quote; doubled quotes stay inside
+			for i++; i < len(sql); i++ {
+				if sql[i] == c {
+					if i+1 < len(sql) && sql[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case '?':
+			text(sql[start:i])
+			if i+1 < len(sql) && sql[i+1] == '?' {
+				i++
+				escaped()
+			} else {
+				marker()
+			}
+			start = i + 1
+		}
+	}
+	text(sql[start:])
+}
+
+// rewriteDollar replaces each $N placeholder outside quoted sections with
+// repl(N); placeholders repl declines are kept as-is.
+func rewriteDollar(sql string, repl func(n int) (string, bool)) string {
+	return rewriteNumbered(sql, "$", repl)
+}
+
+// rewriteNumbered is rewriteDollar for placeholders written prefix+N.
+func rewriteNumbered(sql, prefix string, repl func(n int) (string, bool)) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"':
+			j := i + 1
+			for ; j < len(sql); j++ {
+				if sql[j] == c {
+					if j+1 < len(sql) && sql[j+1] == c {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			if j >= len(sql) {
+				j = len(sql) - 1
+			}
+			b.WriteString(sql[i : j+1])
+			i = j
+		case strings.HasPrefix(sql[i:], prefix) && i+len(prefix) < len(sql) && isDigit(sql[i+len(prefix)]):
+			j := i + len(prefix)
+			for j < len(sql) && isDigit(sql[j]) {
+				j++
+			}
+			n, _ := strconv.Atoi(sql[i+len(prefix) : j])
+			if s, ok := repl(n); ok {
+				b.WriteString(s)
+			} else {
+				b.WriteString(sql[i:j])
+			}
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
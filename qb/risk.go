@@ -0,0 +1,57 @@
+package qb
+
+import "strings"
+
+// RiskReport returns human-readable warnings about risky patterns in the
+// current builder state, e.g. for an admin tool to show before executing.
+// It does not render or modify anything; an empty result means no findings.
+func (qb *QueryBuilder) RiskReport() []string {
+	var risks []string
+
+	switch qb.QueryType {
+	case UPDATE:
+		if len(qb.Conditions) == 0 {
+			if qb.GuardWrites {
+				risks = append(risks, "UPDATE without WHERE (guarded: matches no rows)")
+			} else {
+				risks = append(risks, "UPDATE without WHERE (guard disabled) affects every row")
+			}
+		}
+	case DELETE:
+		if len(qb.Conditions) == 0 {
+			if qb.GuardWrites {
+				risks = append(risks, "DELETE without WHERE (guarded: matches no rows)")
+			} else {
+				risks = append(risks, "DELETE affecting entire table (guard disabled)")
+			}
+		}
+	case SELECT:
+		if !qb.LimitSet && qb.selectsWildcard() {
+			risks = append(risks, "SELECT * without LIMIT may scan a large table")
+		}
+	}
+
+	for _, conds := range [][]Condition{qb.Conditions, qb.HavingConditions} {
+		walkConditions(conds, func(c Condition) {
+			if c.Op != LIKE && c.Op != NOTLIKE {
+				return
+			}
+			if p, ok := c.Value.(string); ok && (strings.HasPrefix(p, "%") || strings.HasPrefix(p, "_")) {
+				risks = append(risks, "LIKE with leading wildcard on "+c.Column+" prevents index use")
+			}
+		})
+	}
+
+	return risks
+}
+
+// walkConditions calls fn for every leaf condition, descending into groups.
+func walkConditions(conds []Condition, fn func(Condition)) {
+	for _, c := range conds {
+		if c.Group != nil {
+			walkConditions(c.Group, fn)
+			continue
+		}
+		fn(c)
+	}
+}
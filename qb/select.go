@@ -1,64 +1,440 @@
 package qb
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 )
 
-// Select starts a SELECT statement and sets the projected columns.
-// When called with no columns, it defaults to SELECT *.
+// Select starts a SELECT statement and replaces the projected columns
+// (including any added by SelectRaw/ SelectExpr). Called with no columns it
+// always means SELECT *, so Select("a").Select() selects *; use SelectRaw to
+// append instead. Unbuilt state of an INSERT/ UPDATE/ DELETE is discarded;
+// other parts of a SELECT chained before it (From, Where, ...) are kept.
 func (qb *QueryBuilder) Select(columns ...string) *QueryBuilder {
+	qb.startStatement(SELECT)
 	qb.QueryType = SELECT
 	if len(columns) == 0 {
-		if qb.Columns == nil {
-			qb.Columns = []string{"*"}
+		columns = []string{"*"}
+	}
+	qb.Columns = columns
+	qb.RawColumns = nil
+	qb.ColumnArgs = nil
+	return qb
+}
+
+// SelectStruct is Select with the columns v maps to, using the same `db`
+// tag rules as the scan helpers (`db:"-"` skips a field, untagged fields use
+// their lower-cased name), so the projection stays in sync with the scan
+// target. v may be a struct, a pointer to one or a (pointer to a) slice of
+// them, e.g. SelectStruct(&users). Anything else is recorded for BuildErr.
+func (qb *QueryBuilder) SelectStruct(v interface{}) *QueryBuilder {
+	t := reflect.TypeOf(v)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || !isStructTarget(t) {
+		qb.addErr("SelectStruct requires a struct, got %T", v)
+		return qb.Select()
+	}
+	return qb.Select(cloneSlice(structFields(t).columns)...)
+}
+
+// SelectAllowed is Select for client-chosen fields such as ?fields=id,name:
+// each requested name is looked up in allowed, which maps API names to real
+// columns (a mapping may carry an alias, "full_name AS name"), so untrusted
+// input never reaches the SQL. Fields not in allowed are dropped and
+// recorded as errors for BuildErr; duplicates are selected once. When no
+// allowed field remains (or none was requested) every allowed column is
+// selected, in sorted API-name order, never SELECT *.
+func (qb *QueryBuilder) SelectAllowed(requested []string, allowed map[string]string) *QueryBuilder {
+	var columns, denied []string
+	seen := make(map[string]bool, len(requested))
+	for _, name := range requested {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
 		}
-	} else {
-		qb.Columns = columns
+		column, ok := allowed[name]
+		if !ok {
+			denied = append(denied, name)
+			continue
+		}
+		seen[name] = true
+		columns = append(columns, column)
 	}
+	if len(columns) == 0 {
+		names := make([]string, 0, len(allowed))
+		for name := range allowed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			columns = append(columns, allowed[name])
+		}
+	}
+	qb.Select(columns...)
+	for _, name := range denied {
+		// after Select, whose statement reset would discard them
+		qb.addErr("select field %q is not allowed", name)
+	}
+	return qb
+}
+
+// QualifyWith prefixes every bare column selected so far with "alias.", so
+// Select("id", "name", "u.email").QualifyWith("u") selects u.id, u.name,
+// u.email, e.g. to disambiguate a base table's columns in a join. Qualified
+// columns, expressions such as COUNT(*), * and SelectRaw entries are left
+// alone; a trailing " AS x" does not stop a column from being qualified.
+func (qb *QueryBuilder) QualifyWith(alias string) *QueryBuilder {
+	for i, col := range qb.Columns {
+		if qb.RawColumns[col] {
+			continue
+		}
+		name := col
+		if loc := aliasRe.FindStringIndex(col); loc != nil {
+			name = col[:loc[0]]
+		}
+		if isBareIdent(name) {
+			qb.Columns[i] = alias + "." + col
+		}
+	}
+	return qb
+}
+
+// isBareIdent reports whether s is a plain unqualified identifier.
+func isBareIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Distinct turns the statement into SELECT DISTINCT.
+func (qb *QueryBuilder) Distinct() *QueryBuilder {
+	qb.DistinctSelect = true
 	return qb
 }
 
+// DistinctOn turns the statement into SELECT DISTINCT ON (columns) ...
+// (PostgreSQL), keeping the first row of each group. PostgreSQL requires the
+// ORDER BY to start with the same expressions, and that ORDER BY is what
+// picks the row that is kept; BuildErr reports an ORDER BY that does not
+// start with columns (in any order among themselves) and other dialects.
+func (qb *QueryBuilder) DistinctOn(columns ...string) *QueryBuilder {
+	qb.DistinctOnColumns = columns
+	return qb
+}
+
+// distinctOnErr checks DistinctOnColumns against the dialect and ORDER BY.
+func (qb *QueryBuilder) distinctOnErr() error {
+	if qb.dialect() != Postgres {
+		return errors.New("qb: DISTINCT ON is only supported by PostgreSQL")
+	}
+	on := qb.DistinctOnColumns
+	if len(qb.OrderByArr) < len(on) {
+		return fmt.Errorf("qb: DISTINCT ON (%s) requires ORDER BY to start with those columns", strings.Join(on, ", "))
+	}
+	lead := make(map[string]bool, len(on))
+	leadCols := make([]string, len(on))
+	for i, o := range qb.OrderByArr[:len(on)] {
+		lead[o.Column] = true
+		leadCols[i] = o.Column
+	}
+	for _, c := range on {
+		if !lead[c] {
+			return fmt.Errorf("qb: DISTINCT ON (%s) requires ORDER BY to start with those columns, got ORDER BY %s",
+				strings.Join(on, ", "), strings.Join(leadCols, ", "))
+		}
+	}
+	return nil
+}
+
 // From sets the source table for SELECT/ DELETE and returns qb.
 func (qb *QueryBuilder) From(table string) *QueryBuilder {
 	qb.Table = table
+	qb.FromValuesTable = nil
+	qb.FromSub, qb.FromSubAlias = nil, ""
+	qb.FromRawExpr, qb.FromRawArgs = "", nil
+	return qb
+}
+
+// FromSubquery uses sub as a derived table: FROM (<sub>) AS alias (without
+// AS on Oracle). sub is rendered into qb's parameter stream at build time and
+// is not reset.
+func (qb *QueryBuilder) FromSubquery(sub *QueryBuilder, alias string) *QueryBuilder {
+	qb.Table = ""
+	qb.FromValuesTable = nil
+	qb.FromSub, qb.FromSubAlias = sub, alias
+	qb.FromRawExpr, qb.FromRawArgs = "", nil
+	return qb
+}
+
+// FromRaw sets the FROM source verbatim, for what From cannot express:
+// table functions, comma-separated tables, ... Each '?' in expr is bound to
+// the next of args ("??" is a literal '?'), e.g.
+// FromRaw("generate_series(?, ?) AS g", 1, 10) renders
+// FROM generate_series($1, $2) AS g. Its args are bound before those of
+// WHERE. expr is neither quoted nor prefixed.
+func (qb *QueryBuilder) FromRaw(expr string, args ...interface{}) *QueryBuilder {
+	if n := countRawPlaceholders(expr); n != len(args) {
+		qb.addErr("from expression %q has %d placeholders but %d args", expr, n, len(args))
+	}
+	qb.Table = ""
+	qb.FromValuesTable = nil
+	qb.FromSub, qb.FromSubAlias = nil, ""
+	qb.FromRawExpr, qb.FromRawArgs = expr, args
+	return qb
+}
+
+// Wrap returns a new builder selecting * from the current SELECT as a
+// derived table, SELECT * FROM (<qb>) AS alias, so conditions can filter on
+// values only available after the inner query runs, e.g. window functions:
+// Wrap("r").Where("rn", EQ, 1). The inner query is a copy of qb, whose
+// parameters are renumbered with the wrapper's; qb itself is left untouched.
+// The wrapper keeps qb's builder configuration.
+func (qb *QueryBuilder) Wrap(alias string) *QueryBuilder {
+	inner := qb.Clone()
+	w := qb.blank()
+	return w.FromSubquery(inner, alias).Select("*")
+}
+
+// PaginateWindow returns a new builder paginating the current SELECT by
+// row number instead of OFFSET, the SQL Server/Oracle pattern:
+// SELECT * FROM (SELECT *, ROW_NUMBER() OVER (ORDER BY ...) AS rn FROM ...)
+// AS t WHERE rn BETWEEN $1 AND $2 ORDER BY rn ASC, with page 1-based. The
+// inner query is a copy of qb without its ORDER BY, LIMIT and OFFSET (order
+// comes from orderCols); qb itself is left untouched.
+func (qb *QueryBuilder) PaginateWindow(orderCols []OrderBy, page, perPage int) *QueryBuilder {
+	if page < 1 {
+		page = 1
+	}
+	inner := qb.Clone()
+	inner.OrderByArr = nil
+	inner.LimitSet, inner.LimitAllSet, inner.OffsetSet = false, false, false
+	if len(inner.Columns) == 0 {
+		inner.Columns = []string{"*"}
+	}
+	inner.SelectRaw("ROW_NUMBER() OVER (ORDER BY " + inner.orderList(orderCols) + ") AS rn")
+
+	low := (page-1)*perPage + 1
+	return inner.Wrap("t").
+		WhereRaw("rn BETWEEN ? AND ?", low, low+perPage-1).
+		OrderBy("rn")
+}
+
+// FromValues uses a VALUES list as the FROM source, e.g.
+// FromValues("t", []string{"id", "name"}, [][]interface{}{{1, "a"}, {2, "b"}})
+// renders FROM (VALUES ($1, $2), ($3, $4)) AS t(id, name). MySQL 8 gets the
+// VALUES ROW(...) form. On PostgreSQL, untyped parameters may need casts.
+// SQLite cannot name the columns of a VALUES list (AS t(id, name)), so a
+// column list there is reported by BuildErr, as is FromValues on Oracle.
+func (qb *QueryBuilder) FromValues(alias string, columns []string, rows [][]interface{}) *QueryBuilder {
+	qb.Table = ""
+	qb.FromSub, qb.FromSubAlias = nil, ""
+	qb.FromRawExpr, qb.FromRawArgs = "", nil
+	qb.FromValuesTable = &ValuesTable{Alias: alias, Columns: columns, Rows: rows}
 	return qb
 }
 
+// valuesTableErr reports a VALUES list the dialect cannot render.
+func (qb *QueryBuilder) valuesTableErr(vt *ValuesTable) error {
+	if qb.dialect() == Oracle {
+		return errors.New("qb: VALUES lists as tables are not supported on Oracle")
+	}
+	if qb.dialect() == SQLite && len(vt.Columns) > 0 {
+		return errors.New("qb: VALUES column aliases (AS " + vt.Alias + "(...)) are not supported by SQLite")
+	}
+	return nil
+}
+
+// renderValuesTable writes (VALUES (...), ...) AS alias(cols), binding values.
+func (qb *QueryBuilder) renderValuesTable(query *strings.Builder, vt *ValuesTable) {
+	rowPrefix := "("
+	if qb.dialect() == MySQL {
+		rowPrefix = "ROW("
+	}
+
+	query.WriteString("(VALUES ")
+	for i, row := range vt.Rows {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString(rowPrefix)
+		for j, v := range row {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			if raw, ok := v.(RawExpr); ok {
+				query.WriteString(string(raw))
+				continue
+			}
+			query.WriteString(qb.placeholder())
+			qb.Parameters = append(qb.Parameters, v)
+		}
+		query.WriteString(")")
+	}
+	query.WriteString(")")
+	qb.writeAlias(query, vt.Alias)
+	if len(vt.Columns) > 0 {
+		query.WriteString("(")
+		query.WriteString(qb.identList(vt.Columns))
+		query.WriteString(")")
+	}
+}
+
+// writeAlias writes a derived table's alias: " AS alias", or " alias" on
+// Oracle, which rejects AS before a table alias.
+func (qb *QueryBuilder) writeAlias(query *strings.Builder, alias string) {
+	if qb.dialect() != Oracle {
+		query.WriteString(" AS")
+	}
+	query.WriteString(" ")
+	query.WriteString(qb.ident(alias))
+}
+
+// writeOrderBy writes the ORDER BY clause, if any.
+func (qb *QueryBuilder) writeOrderBy(query *strings.Builder) {
+	if len(qb.OrderByArr) == 0 {
+		return
+	}
+	query.WriteString(" ORDER BY ")
+	query.WriteString(qb.orderList(qb.OrderByArr))
+}
+
+// orderList renders ORDER BY items ("col ASC, other DESC NULLS LAST").
+func (qb *QueryBuilder) orderList(orders []OrderBy) string {
+	orderParts := make([]string, len(orders))
+	for i, order := range orders {
+		if order.Raw && order.Column == randomOrder {
+			switch qb.dialect() {
+			case MySQL:
+				orderParts[i] = "RAND()"
+			case Oracle:
+				orderParts[i] = "DBMS_RANDOM.VALUE"
+			case SQLServer:
+				orderParts[i] = "NEWID()"
+			default:
+				orderParts[i] = randomOrder
+			}
+			continue
+		}
+		col := order.Column
+		if !order.Raw {
+			col = qb.ident(col)
+		}
+		if order.Desc {
+			orderParts[i] = col + " DESC"
+		} else {
+			orderParts[i] = col + " ASC"
+		}
+		orderParts[i] += qb.nullsSuffix(order.Nulls)
+	}
+	return strings.Join(orderParts, ", ")
+}
+
+// writeFetch writes OFFSET n ROWS and FETCH ... ROWS ONLY, if set.
+func (qb *QueryBuilder) writeFetch(query *strings.Builder) {
+	if qb.OffsetSet {
+		query.WriteString(fmt.Sprintf(" OFFSET %d ROWS", qb.OffsetInt))
+	}
+	if qb.LimitSet {
+		next := "FIRST"
+		if qb.OffsetSet {
+			next = "NEXT"
+		}
+		query.WriteString(fmt.Sprintf(" FETCH %s %d ROWS ONLY", next, qb.LimitInt))
+	}
+}
+
+// useTop reports whether the SQL Server limit renders as SELECT TOP (n):
+// a limit without offset on a plain SELECT (a UNION gets OFFSET/ FETCH).
+func (qb *QueryBuilder) useTop() bool {
+	return qb.dialect() == SQLServer && qb.LimitSet && !qb.OffsetSet && len(qb.SetOps) == 0
+}
+
 func (qb *QueryBuilder) buildSelect() (string, []interface{}) {
+	if len(qb.SetOps) > 0 {
+		return qb.buildSetOps()
+	}
+
 	var query strings.Builder
 
 	// SELECT clause
 	query.WriteString("SELECT ")
-	query.WriteString(strings.Join(qb.Columns, ", "))
+	qb.writeHints(&query)
+	if len(qb.DistinctOnColumns) > 0 {
+		query.WriteString("DISTINCT ON (")
+		query.WriteString(qb.identList(qb.DistinctOnColumns))
+		query.WriteString(") ")
+	} else if qb.DistinctSelect {
+		query.WriteString("DISTINCT ")
+	}
+	if qb.useTop() {
+		query.WriteString(fmt.Sprintf("TOP (%d) ", qb.LimitInt))
+	}
+	for i, col := range qb.Columns {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		if args, ok := qb.ColumnArgs[col]; ok && qb.RawColumns[col] {
+			qb.writeRaw(&query, col, args)
+			continue
+		}
+		query.WriteString(qb.column(col))
+	}
 
 	// FROM clause
-	if qb.Table != "" {
+	if qb.FromRawExpr != "" {
+		query.WriteString(" FROM ")
+		qb.writeRaw(&query, qb.FromRawExpr, qb.FromRawArgs)
+	} else if qb.FromSub != nil {
+		query.WriteString(" FROM (")
+		query.WriteString(qb.renderSub(qb.FromSub))
+		query.WriteString(")")
+		qb.writeAlias(&query, qb.FromSubAlias)
+	} else if qb.FromValuesTable != nil {
+		query.WriteString(" FROM ")
+		qb.renderValuesTable(&query, qb.FromValuesTable)
+	} else if qb.Table != "" {
 		query.WriteString(" FROM ")
-		query.WriteString(qb.Table)
+		query.WriteString(qb.table(qb.Table))
 	}
 
 	// JOIN clause
 	for _, join := range qb.Joins {
 		query.WriteString(" ")
+		if join.Raw != "" {
+			query.WriteString(join.Raw)
+			continue
+		}
 		query.WriteString(string(join.Type))
 		query.WriteString(" ")
-		query.WriteString(join.Table)
-		query.WriteString(" ON ")
-		query.WriteString(join.Condition)
+		query.WriteString(qb.table(join.Table))
+		if join.Condition != "" {
+			query.WriteString(" ON ")
+			query.WriteString(join.Condition)
+		}
 	}
 
 	// WHERE clause
-	if len(qb.Conditions) > 0 {
+	if conds := qb.whereConditions(); len(conds) > 0 {
 		query.WriteString(" WHERE ")
-		qb.buildConditions(&query, qb.Conditions)
+		qb.buildConditions(&query, conds)
 	}
 
 	// GROUP BY clause
-	if len(qb.GroupByColumns) > 0 {
-		query.WriteString(" GROUP BY ")
-		query.WriteString(strings.Join(qb.GroupByColumns, ", "))
-	}
+	qb.writeGroupBy(&query)
 
 	// HAVING clause
 	if len(qb.HavingConditions) > 0 {
@@ -67,28 +443,52 @@ func (qb *QueryBuilder) buildSelect() (string, []interface{}) {
 	}
 
 	// ORDER BY clause
-	if len(qb.OrderByArr) > 0 {
-		query.WriteString(" ORDER BY ")
-		orderParts := make([]string, len(qb.OrderByArr))
-		for i, order := range qb.OrderByArr {
-			if order.Desc {
-				orderParts[i] = order.Column + " DESC"
-			} else {
-				orderParts[i] = order.Column + " ASC"
+	qb.writeOrderBy(&query)
+
+	// LIMIT / OFFSET clauses
+	qb.writeLimitOffset(&query)
+
+	// FOR UPDATE / SHARE ...
+	qb.writeLock(&query)
+
+	return query.String(), qb.Parameters
+}
+
+// writeLimitOffset writes the LIMIT and OFFSET clauses, if set; Oracle and
+// SQL Server get the standard OFFSET n ROWS FETCH {FIRST|NEXT} m ROWS ONLY
+// form instead (a SQL Server limit without offset is TOP, see useTop).
+func (qb *QueryBuilder) writeLimitOffset(query *strings.Builder) {
+	switch qb.dialect() {
+	case Oracle:
+		qb.writeFetch(query)
+		return
+	case SQLServer:
+		if !qb.useTop() {
+			if qb.LimitSet && !qb.OffsetSet {
+				// FETCH requires OFFSET on SQL Server
+				query.WriteString(" OFFSET 0 ROWS")
+				query.WriteString(fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", qb.LimitInt))
+				return
 			}
+			qb.writeFetch(query)
 		}
-		query.WriteString(strings.Join(orderParts, ", "))
+		return
 	}
-
-	// LIMIT clause
-	if qb.LimitInt > 0 {
+	if qb.LimitSet {
 		query.WriteString(fmt.Sprintf(" LIMIT %d", qb.LimitInt))
+	} else if qb.LimitAllSet && qb.dialect() == Postgres {
+		query.WriteString(" LIMIT ALL")
+	} else if qb.OffsetSet {
+		// MySQL and SQLite only accept OFFSET after a LIMIT, so spell
+		// "no limit" the way each documents it
+		switch qb.dialect() {
+		case MySQL:
+			query.WriteString(" LIMIT 18446744073709551615")
+		case SQLite:
+			query.WriteString(" LIMIT -1")
+		}
 	}
-
-	// OFFSET clause
-	if qb.OffsetInt > 0 {
+	if qb.OffsetSet {
 		query.WriteString(fmt.Sprintf(" OFFSET %d", qb.OffsetInt))
 	}
-
-	return query.String(), qb.Parameters
 }
@@ -0,0 +1,86 @@
+package qb
+
+// renderCtx carries the placeholder style, counter and parameter accumulator
+// while a statement and its nested sub-builders render into one stream.
+type renderCtx struct {
+	style   PlaceholderStyle
+	dialect Dialect
+	quote   bool
+	prefix  string
+	index   int
+	params  []interface{}
+}
+
+// renderInto renders qb into ctx's parameter stream, continuing its
+// placeholder numbering, without calling Build or Reset. Placeholder style
+// and dialect follow ctx; quoting and the table prefix are inherited unless
+// qb sets its own (WithQuoting(false) included). qb's own state is left
+// unchanged, so a sub-builder can be reused.
+func (qb *QueryBuilder) renderInto(ctx *renderCtx) string {
+	saved := *qb
+
+	qb.PhStyle = ctx.style
+	if qb.Dialect == DialectAuto {
+		qb.Dialect = ctx.dialect
+	}
+	if !qb.quoteSet {
+		qb.QuoteIdents = qb.QuoteIdents || ctx.quote
+	}
+	if qb.TablePrefix == "" {
+		qb.TablePrefix = ctx.prefix
+	}
+	qb.ParamIndex = ctx.index
+	qb.Parameters = ctx.params
+
+	sql, params := qb.renderStatement()
+	ctx.index, ctx.params = qb.ParamIndex, params
+
+	*qb = saved
+	return sql
+}
+
+// renderSub renders sub as part of qb's statement and returns its SQL; sub's
+// parameters are appended to qb's and its placeholders continue qb's count.
+func (qb *QueryBuilder) renderSub(sub *QueryBuilder) string {
+	ctx := &renderCtx{
+		style:   qb.PhStyle,
+		dialect: qb.Dialect,
+		quote:   qb.QuoteIdents,
+		prefix:  qb.TablePrefix,
+		index:   qb.ParamIndex,
+		params:  qb.Parameters,
+	}
+	sql := sub.renderInto(ctx)
+	qb.ParamIndex, qb.Parameters = ctx.index, ctx.params
+	return sql
+}
+
+// WhereInSub adds "column IN (<sub>)" combined with AND. sub is rendered when
+// qb is built, sharing its placeholder numbering; sub itself is not reset.
+func (qb *QueryBuilder) WhereInSub(column string, sub *QueryBuilder) *QueryBuilder {
+	return qb.Where(column, IN, sub)
+}
+
+// WhereTupleInSub adds "(a, b) IN (<sub>)" combined with AND, for
+// composite-key filters; sub must select as many columns as columns lists,
+// which BuildErr checks unless sub selects a wildcard. SQLite needs 3.15+.
+func (qb *QueryBuilder) WhereTupleInSub(columns []string, sub *QueryBuilder) *QueryBuilder {
+	qb.Where("", IN, sub)
+	qb.Conditions[len(qb.Conditions)-1].Tuple = columns
+	return qb
+}
+
+// WhereNotInSub adds "column NOT IN (<sub>)" combined with AND.
+func (qb *QueryBuilder) WhereNotInSub(column string, sub *QueryBuilder) *QueryBuilder {
+	return qb.Where(column, NIN, sub)
+}
+
+// WhereExists adds "EXISTS (<sub>)" combined with AND.
+func (qb *QueryBuilder) WhereExists(sub *QueryBuilder) *QueryBuilder {
+	return qb.Where("", EXISTS, sub)
+}
+
+// WhereNotExists adds "NOT EXISTS (<sub>)" combined with AND.
+func (qb *QueryBuilder) WhereNotExists(sub *QueryBuilder) *QueryBuilder {
+	return qb.Where("", NEXISTS, sub)
+}
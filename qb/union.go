@@ -0,0 +1,87 @@
+package qb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Set operators stored in SetOp.Op.
+const (
+	unionOp    = "UNION"
+	unionAllOp = "UNION ALL"
+)
+
+// Union combines the SELECT with sub, removing duplicate rows:
+// (SELECT ...) UNION (SELECT ...). Once qb has a set operation, its own
+// ORDER BY, LIMIT and OFFSET apply to the combined result, wherever they are
+// chained, e.g.
+//
+//	NewQB().Select("id", "created_at").From("posts").
+//		UnionAll(NewQB().Select("id", "created_at").From("comments")).
+//		OrderByDesc("created_at").Limit(10)
+//
+// To order or limit one member alone, set it on sub (or Wrap qb first).
+// sub is rendered into qb's parameter stream at build time and is not reset.
+// Every member must select the same number of columns.
+func (qb *QueryBuilder) Union(sub *QueryBuilder) *QueryBuilder {
+	qb.SetOps = append(qb.SetOps, SetOp{Op: unionOp, Query: sub})
+	return qb
+}
+
+// UnionAll is like Union but keeps duplicate rows (UNION ALL).
+func (qb *QueryBuilder) UnionAll(sub *QueryBuilder) *QueryBuilder {
+	qb.SetOps = append(qb.SetOps, SetOp{Op: unionAllOp, Query: sub})
+	return qb
+}
+
+// buildSetOps renders qb's SELECT and its set-operation members, each
+// parenthesized, followed by the ORDER BY/ LIMIT/ OFFSET of the whole. SQLite
+// does not accept parenthesized members, so there they are written bare.
+func (qb *QueryBuilder) buildSetOps() (string, []interface{}) {
+	head := qb.Clone()
+	head.CTEs, head.SetOps = nil, nil
+	head.OrderByArr = nil
+	head.LimitSet, head.LimitAllSet, head.OffsetSet = false, false, false
+
+	open, closing := "(", ")"
+	if qb.dialect() == SQLite {
+		open, closing = "", ""
+	}
+
+	var query strings.Builder
+	query.WriteString(open)
+	query.WriteString(qb.renderSub(head))
+	query.WriteString(closing)
+	for _, op := range qb.SetOps {
+		query.WriteString(" ")
+		query.WriteString(op.Op)
+		query.WriteString(" ")
+		query.WriteString(open)
+		query.WriteString(qb.renderSub(op.Query))
+		query.WriteString(closing)
+	}
+
+	qb.writeOrderBy(&query)
+	qb.writeLimitOffset(&query)
+	return query.String(), qb.Parameters
+}
+
+// setOpErrs reports problems of the set-operation members: their own
+// errors, column count mismatches and, on SQLite, members that order or
+// limit themselves.
+func (qb *QueryBuilder) setOpErrs() []error {
+	var errs []error
+	for i, op := range qb.SetOps {
+		sub := op.Query
+		errs = append(errs, sub.Errs...)
+		errs = append(errs, sub.validate()...)
+		if !qb.selectsWildcard() && !sub.selectsWildcard() && len(sub.Columns) != len(qb.Columns) {
+			errs = append(errs, fmt.Errorf("qb: %s member %d selects %d columns, want %d", op.Op, i+1, len(sub.Columns), len(qb.Columns)))
+		}
+		if qb.dialect() == SQLite && (len(sub.OrderByArr) > 0 || sub.LimitSet || sub.OffsetSet) {
+			errs = append(errs, errors.New("qb: SQLite does not allow ORDER BY/ LIMIT on a compound SELECT member"))
+		}
+	}
+	return errs
+}
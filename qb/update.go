@@ -1,13 +1,16 @@
 package qb
 
 import (
-	"sort"
+	"fmt"
+	"slices"
 	"strings"
 )
 
-// Update starts an UPDATE statement for the given table and initializes UpdateData.
+// Update starts an UPDATE statement for the given table and initializes UpdateData,
+// discarding any unbuilt per-query state (builder configuration is kept).
 // Use SetUpdate to add assignments. Supports RETURNING on dialects that allow it.
 func (qb *QueryBuilder) Update(table string) *QueryBuilder {
+	qb.startStatement(UPDATE)
 	qb.QueryType = UPDATE
 	qb.Table = table
 	qb.UpdateData = make(map[string]interface{})
@@ -23,40 +26,136 @@ func (qb *QueryBuilder) SetUpdate(column string, value interface{}) *QueryBuilde
 	return qb
 }
 
+// SetUpdateIf is like SetUpdate but adds the assignment only when cond is
+// true, e.g. for PATCH-style partial updates:
+// SetUpdateIf(in.Name != nil, "name", in.Name). An UPDATE left without any
+// assignment is reported by BuildErr.
+func (qb *QueryBuilder) SetUpdateIf(cond bool, column string, value interface{}) *QueryBuilder {
+	if !cond {
+		return qb
+	}
+	return qb.SetUpdate(column, value)
+}
+
+// UpdateManyFromValues updates many rows with different values in one
+// statement (PostgreSQL):
+//
+//	UPDATE t SET name = v.name FROM (VALUES ($1, $2), ($3, $4)) AS v(id, name) WHERE t.id = v.id
+//
+// Every row must hold keyColumn plus the same columns to set; all values are
+// bound. Further Where conditions are ANDed after the join predicate. On
+// PostgreSQL untyped parameters may need casts, as with FromValues. Rows
+// that differ in columns, a missing key, MySQL, Oracle (which has no
+// UPDATE ... FROM) and SQLite (whose VALUES lists cannot name their columns)
+// are reported by BuildErr.
+func (qb *QueryBuilder) UpdateManyFromValues(table string, keyColumn string, rows []map[string]interface{}) *QueryBuilder {
+	qb.Update(table)
+	if i := batchMismatch(rows); i >= 0 {
+		qb.addErr("UpdateManyFromValues row %d has different columns than row 0", i)
+	}
+
+	columns := batchColumns(rows)
+	values := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = make([]interface{}, len(columns))
+		for j, col := range columns {
+			values[i][j] = row[col]
+		}
+	}
+	qb.UpdateValues = &ValuesTable{Alias: "v", Columns: columns, Rows: values}
+	qb.UpdateKey = keyColumn
+	return qb
+}
+
+// updateValuesErrs validates an UpdateManyFromValues statement.
+func (qb *QueryBuilder) updateValuesErrs() []error {
+	var errs []error
+	if d := qb.dialect(); d == MySQL || d == Oracle {
+		errs = append(errs, fmt.Errorf("qb: UpdateManyFromValues is not supported on %s", d))
+	} else if err := qb.valuesTableErr(qb.UpdateValues); err != nil {
+		errs = append(errs, err)
+	}
+	if !slices.Contains(qb.UpdateValues.Columns, qb.UpdateKey) {
+		errs = append(errs, fmt.Errorf("qb: UpdateManyFromValues rows lack key column %q", qb.UpdateKey))
+	}
+	return errs
+}
+
+// tableRef returns how columns of the table reference s are qualified: its
+// alias ("users u", "users AS u") or the rendered table name.
+func (qb *QueryBuilder) tableRef(s string) string {
+	if fields := strings.Fields(s); len(fields) > 1 {
+		return qb.ident(fields[len(fields)-1])
+	}
+	return qb.table(s)
+}
+
+// buildUpdateFromValues renders an UpdateManyFromValues statement.
+func (qb *QueryBuilder) buildUpdateFromValues() (string, []interface{}) {
+	var query strings.Builder
+	vt := qb.UpdateValues
+
+	query.WriteString("UPDATE ")
+	query.WriteString(qb.table(qb.Table))
+	query.WriteString(" SET ")
+	setParts := make([]string, 0, len(vt.Columns))
+	for _, col := range vt.Columns {
+		if col != qb.UpdateKey {
+			setParts = append(setParts, qb.ident(col)+" = "+qb.ident(vt.Alias+"."+col))
+		}
+	}
+	query.WriteString(strings.Join(setParts, ", "))
+
+	query.WriteString(" FROM ")
+	qb.renderValuesTable(&query, vt)
+
+	query.WriteString(" WHERE ")
+	query.WriteString(qb.tableRef(qb.Table) + "." + qb.ident(qb.UpdateKey))
+	query.WriteString(" = ")
+	query.WriteString(qb.ident(vt.Alias + "." + qb.UpdateKey))
+	if conds := qb.whereConditions(); len(conds) > 0 {
+		query.WriteString(" AND ")
+		if len(conds) > 1 {
+			conds = []Condition{{Logic: "AND", Group: conds}}
+		}
+		qb.buildConditions(&query, conds)
+	}
+
+	qb.renderReturning(&query)
+	return query.String(), qb.Parameters
+}
+
 func (qb *QueryBuilder) buildUpdate() (string, []interface{}) {
+	if qb.UpdateValues != nil {
+		return qb.buildUpdateFromValues()
+	}
+
 	var query strings.Builder
 
 	query.WriteString("UPDATE ")
-	query.WriteString(qb.Table)
+	query.WriteString(qb.table(qb.Table))
 	query.WriteString(" SET ")
 
 	// Stable order for update set clauses
-	keys := make([]string, 0, len(qb.UpdateData))
-	for k := range qb.UpdateData {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
+	keys := sortedKeys(qb.UpdateData)
 
 	setParts := make([]string, 0, len(keys))
 	for _, column := range keys {
-		setParts = append(setParts, column+" = "+qb.placeholder())
+		setParts = append(setParts, qb.ident(column)+" = "+qb.placeholder())
 		qb.Parameters = append(qb.Parameters, qb.UpdateData[column])
 	}
 	query.WriteString(strings.Join(setParts, ", "))
 
-	// WHERE clause
-	if len(qb.Conditions) > 0 {
+	// WHERE clause (scope conditions alone do not satisfy the guard)
+	if len(qb.Conditions) == 0 && qb.GuardWrites {
+		query.WriteString(guardWhere)
+	} else if conds := qb.whereConditions(); len(conds) > 0 {
 		query.WriteString(" WHERE ")
-		qb.buildConditions(&query, qb.Conditions)
-	} else if qb.GuardWrites {
-		query.WriteString(" WHERE 1=0 /*guarded: mising WHERE */")
+		qb.buildConditions(&query, conds)
 	}
 
 	// RETURNING
-	if len(qb.ReturningColumns) > 0 {
-		query.WriteString(" RETURNING ")
-		query.WriteString(strings.Join(qb.ReturningColumns, ", "))
-	}
+	qb.renderReturning(&query)
 
 	return query.String(), qb.Parameters
 }
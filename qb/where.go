@@ -1,5 +1,11 @@
 package qb
 
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
 // Where adds a WHERE predicate combined with AND.
 func (qb *QueryBuilder) Where(column string, op Operator, value interface{}) *QueryBuilder {
 	condition := Condition{
@@ -12,6 +18,15 @@ func (qb *QueryBuilder) Where(column string, op Operator, value interface{}) *Qu
 	return qb
 }
 
+// WhereNot adds a negated predicate "NOT (column op $n)" combined with AND.
+// Unlike NEQ it negates any operator, e.g. WhereNot("name", LIKE, "a%")
+// renders "NOT (name LIKE $1)"; the value is bound as with Where.
+func (qb *QueryBuilder) WhereNot(column string, op Operator, value interface{}) *QueryBuilder {
+	qb.Where(column, op, value)
+	qb.Conditions[len(qb.Conditions)-1].Not = true
+	return qb
+}
+
 // OrWhere adds a WHERE predicate combined with OR.
 func (qb *QueryBuilder) OrWhere(column string, op Operator, value interface{}) *QueryBuilder {
 	condition := Condition{
@@ -24,16 +39,186 @@ func (qb *QueryBuilder) OrWhere(column string, op Operator, value interface{}) *
 	return qb
 }
 
+// Scope injects a filter that is always ANDed with the whole user WHERE, as a
+// scoping layer would for tenant_id. When the user conditions contain more
+// than one predicate they are parenthesized first, so
+// Where(a).OrWhere(b).Scope("tenant_id", EQ, 7) renders
+// "WHERE (a OR b) AND tenant_id = $n" rather than "a OR b AND tenant_id = $n".
+// Scopes do not satisfy the write guard on their own. A scope may be added
+// before the statement's entry point (Select/ Insert/ Update/ Delete); it
+// lasts until the next Build or Reset.
+func (qb *QueryBuilder) Scope(column string, op Operator, value interface{}) *QueryBuilder {
+	qb.ScopeConditions = append(qb.ScopeConditions, Condition{Column: column, Op: op, Value: value, Logic: "AND"})
+	return qb
+}
+
+// RenderWhere renders just the WHERE predicates (scopes included, no WHERE
+// keyword), DollarN placeholders numbered from $startIndex, and returns
+// them with their args and the next free index, like Renumber, so the same
+// filter can be spliced into hand-written statements. It neither builds nor
+// resets qb. Without conditions it returns "", no args and startIndex.
+func (qb *QueryBuilder) RenderWhere(startIndex int) (string, []interface{}, int) {
+	conds := qb.whereConditions()
+	if len(conds) == 0 {
+		return "", nil, startIndex
+	}
+
+	params, index := qb.Parameters, qb.ParamIndex
+	defer func() { qb.Parameters, qb.ParamIndex = params, index }()
+	qb.Parameters, qb.ParamIndex = []interface{}{}, startIndex-1
+
+	var query strings.Builder
+	qb.buildConditions(&query, conds)
+	args := qb.Parameters
+	for i, a := range args {
+		if n, ok := a.(NamedArg); ok {
+			args[i] = n.Value
+		}
+	}
+	return query.String(), args, startIndex + len(args)
+}
+
+// whereConditions returns the conditions to render after WHERE: the user
+// conditions (wrapped in a group when scopes follow and there is more than
+// one) followed by the scope conditions.
+func (qb *QueryBuilder) whereConditions() []Condition {
+	if len(qb.ScopeConditions) == 0 {
+		return qb.Conditions
+	}
+	conds := make([]Condition, 0, 1+len(qb.ScopeConditions))
+	switch len(qb.Conditions) {
+	case 0:
+	case 1:
+		conds = append(conds, qb.Conditions[0])
+	default:
+		conds = append(conds, Condition{Logic: "AND", Group: qb.Conditions})
+	}
+	for _, s := range qb.ScopeConditions {
+		s.Logic = "AND"
+		conds = append(conds, s)
+	}
+	return conds
+}
+
+// WhereGroup adds a parenthesized group of conditions combined with AND.
+// fn receives a scratch builder; only the WHERE conditions it adds are used.
+// Example: WhereGroup(func(g *QueryBuilder) { g.Where("a", EQ, 1).OrWhere("b", EQ, 2) })
+// renders "... AND (a = $1 OR b = $2)". An empty group adds nothing.
+func (qb *QueryBuilder) WhereGroup(fn func(*QueryBuilder)) *QueryBuilder {
+	return qb.whereGroup("AND", false, fn)
+}
+
+// OrWhereGroup is like WhereGroup but combines the group with OR.
+func (qb *QueryBuilder) OrWhereGroup(fn func(*QueryBuilder)) *QueryBuilder {
+	return qb.whereGroup("OR", false, fn)
+}
+
+// WhereNotGroup is like WhereGroup but negates the group:
+// "... AND NOT (a = $1 OR b = $2)".
+func (qb *QueryBuilder) WhereNotGroup(fn func(*QueryBuilder)) *QueryBuilder {
+	return qb.whereGroup("AND", true, fn)
+}
+
+func (qb *QueryBuilder) whereGroup(logic string, not bool, fn func(*QueryBuilder)) *QueryBuilder {
+	scratch := &QueryBuilder{}
+	fn(scratch)
+	qb.Errs = append(qb.Errs, scratch.Errs...)
+	if len(scratch.Conditions) > 0 {
+		qb.Conditions = append(qb.Conditions, Condition{Logic: logic, Group: scratch.Conditions, Not: not})
+	}
+	return qb
+}
+
+// WhereAnyOf adds a group matching any of the given filter sets, combined
+// with AND: each map becomes an AND-group of equalities in sorted column
+// order and the groups are ORed, e.g. "((a = $1 AND b = $2) OR (a = $3 AND
+// c = $4))". A nil value matches IS NULL. Empty maps are skipped, and
+// without any filter set nothing is added.
+func (qb *QueryBuilder) WhereAnyOf(maps ...map[string]interface{}) *QueryBuilder {
+	return qb.WhereGroup(func(or *QueryBuilder) {
+		for _, m := range maps {
+			or.OrWhereGroup(func(g *QueryBuilder) {
+				for _, col := range sortedKeys(m) {
+					if m[col] == nil {
+						g.Where(col, NULL, nil)
+					} else {
+						g.Where(col, EQ, m[col])
+					}
+				}
+			})
+		}
+	})
+}
+
+// WhereLogic adds a WHERE predicate whose combinator is chosen at runtime:
+// logic is "AND" or "OR" (case-insensitive). Any other value falls back to
+// AND and is recorded as an error for BuildErr.
+func (qb *QueryBuilder) WhereLogic(logic string, column string, op Operator, value interface{}) *QueryBuilder {
+	switch strings.ToUpper(strings.TrimSpace(logic)) {
+	case "OR":
+		return qb.OrWhere(column, op, value)
+	case "AND":
+	default:
+		qb.addErr("invalid WHERE logic %q (want AND or OR)", logic)
+	}
+	return qb.Where(column, op, value)
+}
+
+// WhereCast adds an AND predicate whose placeholder carries a PostgreSQL cast,
+// e.g. WhereCast("id", EQ, v, "uuid") renders "id = $1::uuid" with v bound;
+// Oracle and SQL Server get the standard "id = CAST(:1 AS uuid)".
+func (qb *QueryBuilder) WhereCast(column string, op Operator, value interface{}, castType string) *QueryBuilder {
+	qb.Where(column, op, value)
+	qb.Conditions[len(qb.Conditions)-1].Cast = castType
+	return qb
+}
+
+// WhereConditions appends prebuilt conditions, respecting each one's Logic.
+// Logic is normalized to upper case; anything other than "AND"/"OR"
+// (including empty) defaults to "AND". The Logic of whichever condition ends
+// up first in the WHERE clause is never rendered.
+func (qb *QueryBuilder) WhereConditions(conds ...Condition) *QueryBuilder {
+	for _, c := range conds {
+		c.Logic = normalizeLogic(c.Logic)
+		qb.Conditions = append(qb.Conditions, c)
+	}
+	return qb
+}
+
+// normalizeLogic returns "OR" for any casing of "or" and "AND" otherwise.
+func normalizeLogic(logic string) string {
+	if strings.EqualFold(strings.TrimSpace(logic), "OR") {
+		return "OR"
+	}
+	return "AND"
+}
+
 // WhereIn adds an IN (...) predicate; accepts any slice/array as value.
+// Any other value renders as an empty list "(1=0)" and is reported by
+// BuildErr; use WhereInVals for individual values. Nil elements bind NULL,
+// but col IN (NULL) never matches (and NOT IN with a NULL matches no row),
+// so BuildErr reports them; OR an IS NULL condition instead.
 func (qb *QueryBuilder) WhereIn(column string, value interface{}) *QueryBuilder {
 	return qb.Where(column, IN, value)
 }
 
 // WhereNotIn adds a NOT IN (...) predicate; accepts any slice/array as value.
+// As with WhereIn, a non-slice value is reported by BuildErr.
 func (qb *QueryBuilder) WhereNotIn(column string, value interface{}) *QueryBuilder {
 	return qb.Where(column, NIN, value)
 }
 
+// WhereInVals is a variadic WhereIn: WhereInVals("id", 1, 2, 3) renders
+// "id IN ($1, $2, $3)" and a single value renders "id IN ($1)", unlike
+// WhereIn, where a non-slice value counts as an empty list. With no values it
+// renders "(1=0)" like an empty WhereIn.
+func (qb *QueryBuilder) WhereInVals(column string, values ...interface{}) *QueryBuilder {
+	if values == nil {
+		values = []interface{}{}
+	}
+	return qb.Where(column, IN, values)
+}
+
 // WhereLike adds a LIKE predicate (value should include wildcards, e.g. %foo%).
 func (qb *QueryBuilder) WhereLike(column, pattern string) *QueryBuilder {
 	return qb.Where(column, LIKE, pattern)
@@ -44,6 +229,191 @@ func (qb *QueryBuilder) WhereNotLike(column, pattern string) *QueryBuilder {
 	return qb.Where(column, NOTLIKE, pattern)
 }
 
+// WhereJSONHasKey adds "column ? key" (PostgreSQL jsonb: top-level key exists).
+func (qb *QueryBuilder) WhereJSONHasKey(column, key string) *QueryBuilder {
+	return qb.Where(column, HASKEY, key)
+}
+
+// WhereJSONHasAnyKey adds "column ?| keys" (PostgreSQL jsonb: any key exists).
+// keys is bound as a single text[] parameter; drivers such as lib/pq need it
+// wrapped (pq.Array) while pgx binds []string directly.
+func (qb *QueryBuilder) WhereJSONHasAnyKey(column string, keys []string) *QueryBuilder {
+	return qb.Where(column, HASANY, keys)
+}
+
+// WhereNullSafeEq adds a NULL-safe equality that also matches when both sides
+// are NULL: "col IS NOT DISTINCT FROM $1" on PostgreSQL, "col <=> ?" on MySQL,
+// "col IS ?" on SQLite, "DECODE(col, :1, 0, 1) = 0" on Oracle and
+// "EXISTS (SELECT col INTERSECT SELECT @p1)" on SQL Server. The value is
+// bound.
+func (qb *QueryBuilder) WhereNullSafeEq(column string, value interface{}) *QueryBuilder {
+	return qb.Where(column, NOTDISTINCT, value)
+}
+
+// WhereColumn compares two columns without binding, combined with AND, e.g.
+// WhereColumn("o.user_id", EQ, "u.id") renders "o.user_id = u.id". Inside a
+// sub-builder this is how a correlated subquery references the outer query.
+func (qb *QueryBuilder) WhereColumn(left string, op Operator, right string) *QueryBuilder {
+	return qb.Where(left, op, ColumnRef(right))
+}
+
+// valueBetweenColumns is the condition value of WhereValueBetweenColumns: a
+// bound value tested against two column bounds.
+type valueBetweenColumns struct {
+	Value     interface{}
+	Low, High string
+}
+
+// WhereValueBetweenColumns adds "$1 BETWEEN lowCol AND highCol" combined with
+// AND, binding value once, e.g. to find the range stored in
+// valid_from/valid_to that contains a timestamp. The columns are not bound.
+func (qb *QueryBuilder) WhereValueBetweenColumns(value interface{}, lowCol, highCol string) *QueryBuilder {
+	qb.Conditions = append(qb.Conditions, Condition{
+		Op:    "BETWEEN",
+		Value: valueBetweenColumns{Value: value, Low: lowCol, High: highCol},
+		Logic: "AND",
+	})
+	return qb
+}
+
+// WhereRange adds the inclusive range "column >= $1 AND column <= $2" as two
+// AND predicates. It matches what BETWEEN would select, but keeps the
+// explicit operators (and two conditions for GetConditions) for those who
+// want them spelled out.
+func (qb *QueryBuilder) WhereRange(column string, low, high interface{}) *QueryBuilder {
+	return qb.Where(column, GTE, low).Where(column, LTE, high)
+}
+
+// WhereOpenParen opens a manual group combined with AND: everything up to
+// the matching WhereCloseParen is parenthesized, so
+// WhereOpenParen().Where("a", EQ, 1).OrWhere("b", EQ, 2).WhereCloseParen().Where("c", EQ, 3)
+// renders "(a = $1 OR b = $2) AND c = $3". It is the low-level alternative
+// to WhereGroup; unbalanced markers are reported by BuildErr.
+func (qb *QueryBuilder) WhereOpenParen() *QueryBuilder {
+	qb.Conditions = append(qb.Conditions, Condition{Op: openParen, Logic: "AND"})
+	return qb
+}
+
+// OrWhereOpenParen is WhereOpenParen combined with OR.
+func (qb *QueryBuilder) OrWhereOpenParen() *QueryBuilder {
+	qb.Conditions = append(qb.Conditions, Condition{Op: openParen, Logic: "OR"})
+	return qb
+}
+
+// WhereCloseParen closes the group opened by WhereOpenParen.
+func (qb *QueryBuilder) WhereCloseParen() *QueryBuilder {
+	qb.Conditions = append(qb.Conditions, Condition{Op: closeParen})
+	return qb
+}
+
+// parensBalanced reports whether the paren markers in conds (groups
+// included) nest properly.
+func parensBalanced(conds []Condition) bool {
+	depth := 0
+	for _, c := range conds {
+		switch {
+		case c.Group != nil:
+			if !parensBalanced(c.Group) {
+				return false
+			}
+		case c.Op == openParen:
+			depth++
+		case c.Op == closeParen:
+			if depth--; depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// WhereRaw adds a raw predicate combined with AND. Each '?' in expr is
+// replaced by the builder's placeholder and bound to the next arg; write "??"
+// for a literal '?'. A *QueryBuilder arg renders as a parenthesized subquery
+// instead, e.g. WhereRaw("price > ALL ?", sub). expr is inlined verbatim
+// (never quoted), so parenthesize it yourself when it contains OR. A
+// placeholder/arg count mismatch is recorded for BuildErr.
+func (qb *QueryBuilder) WhereRaw(expr string, args ...interface{}) *QueryBuilder {
+	return qb.whereRaw("AND", expr, args)
+}
+
+// OrWhereRaw is like WhereRaw but combines the predicate with OR.
+func (qb *QueryBuilder) OrWhereRaw(expr string, args ...interface{}) *QueryBuilder {
+	return qb.whereRaw("OR", expr, args)
+}
+
+func (qb *QueryBuilder) whereRaw(logic, expr string, args []interface{}) *QueryBuilder {
+	if n := countRawPlaceholders(expr); n != len(args) {
+		qb.addErr("raw condition %q has %d placeholders but %d args", expr, n, len(args))
+	}
+	qb.Conditions = append(qb.Conditions, Condition{Raw: expr, Value: args, Logic: logic})
+	return qb
+}
+
+// arrayLenFunc is the PostgreSQL array length function; function translates
+// it for other dialects.
+const arrayLenFunc = "cardinality"
+
+// WhereArrayLen filters on the number of elements in an array column,
+// combined with AND: "cardinality(column) op $1" on PostgreSQL,
+// "JSON_LENGTH(column) op ?" on MySQL (JSON arrays),
+// "json_array_length(column) op ?" on SQLite,
+// "JSON_VALUE(column, '$.size()' RETURNING NUMBER) op :1" on Oracle and
+// "(SELECT COUNT(*) FROM OPENJSON(column)) op @p1" on SQL Server. n is bound.
+func (qb *QueryBuilder) WhereArrayLen(column string, op Operator, n int) *QueryBuilder {
+	qb.Conditions = append(qb.Conditions, Condition{Column: column, Op: op, Value: n, Logic: "AND", Func: arrayLenFunc})
+	return qb
+}
+
+// Date-part helper functions; funcCall renders them per dialect.
+const (
+	yearFunc  = "year"
+	monthFunc = "month"
+	dateFunc  = "date"
+)
+
+// sqliteDatePart maps date-part helpers to SQLite strftime formats.
+var sqliteDatePart = map[string]string{yearFunc: "%Y", monthFunc: "%m"}
+
+// WhereYear filters on the year of a date/time column, combined with AND:
+// "EXTRACT(YEAR FROM column) op $1" on PostgreSQL, "YEAR(column) op ?" on
+// MySQL/SQL Server and "CAST(strftime('%Y', column) AS INTEGER) op ?" on
+// SQLite.
+func (qb *QueryBuilder) WhereYear(column string, op Operator, year int) *QueryBuilder {
+	qb.Conditions = append(qb.Conditions, Condition{Column: column, Op: op, Value: year, Logic: "AND", Func: yearFunc})
+	return qb
+}
+
+// WhereMonth filters on the month (1-12) of a date/time column, combined
+// with AND; see WhereYear for the per-dialect forms.
+func (qb *QueryBuilder) WhereMonth(column string, op Operator, month int) *QueryBuilder {
+	qb.Conditions = append(qb.Conditions, Condition{Column: column, Op: op, Value: month, Logic: "AND", Func: monthFunc})
+	return qb
+}
+
+// WhereDate filters on the calendar date of a date/time column, combined
+// with AND: "date(column) op $1" ("DATE(column)" on MySQL, "CAST(column AS
+// DATE)" on SQL Server, "TRUNC(column) op TO_DATE(:1, 'YYYY-MM-DD')" on
+// Oracle). The date is bound as a "2006-01-02" string, which every dialect
+// compares as a date; its time of day and location are ignored.
+func (qb *QueryBuilder) WhereDate(column string, op Operator, date time.Time) *QueryBuilder {
+	qb.Conditions = append(qb.Conditions, Condition{Column: column, Op: op, Value: date.Format(time.DateOnly), Logic: "AND", Func: dateFunc})
+	return qb
+}
+
+// WhereTrue adds an always-true predicate "(1=1)" combined with AND, the same
+// form an empty NOT IN renders. Handy as a base for OR-ed optional filters.
+func (qb *QueryBuilder) WhereTrue() *QueryBuilder {
+	return qb.whereRaw("AND", "(1=1)", nil)
+}
+
+// WhereFalse adds an always-false predicate "(1=0)" combined with AND, the
+// same form an empty IN renders; e.g. WhereFalse().OrWhere(...) matches only
+// the OR-ed filters.
+func (qb *QueryBuilder) WhereFalse() *QueryBuilder {
+	return qb.whereRaw("AND", "(1=0)", nil)
+}
+
 // WhereNull adds an IS NULL predicate.
 func (qb *QueryBuilder) WhereNull(column string) *QueryBuilder {
 	return qb.Where(column, NULL, nil)
@@ -53,3 +423,92 @@ func (qb *QueryBuilder) WhereNull(column string) *QueryBuilder {
 func (qb *QueryBuilder) WhereNotNull(column string) *QueryBuilder {
 	return qb.Where(column, NOTNULL, nil)
 }
+
+// structOps maps operator names usable in `qb` struct tags to Operators.
+var structOps = map[string]Operator{
+	"eq":      EQ,
+	"neq":     NEQ,
+	"gt":      GT,
+	"gte":     GTE,
+	"lt":      LT,
+	"lte":     LTE,
+	"in":      IN,
+	"nin":     NIN,
+	"null":    NULL,
+	"notnull": NOTNULL,
+	"like":    LIKE,
+	"notlike": NOTLIKE,
+}
+
+// WhereStruct adds one AND predicate per struct field tagged with
+// `qb:"column[,op][,omitempty]"`, e.g. `qb:"age,gte"` or
+// `qb:"name,like,omitempty"`. The operator defaults to eq; omitempty skips
+// zero-value fields (nil pointers included). Pointer fields are dereferenced;
+// a nil one without omitempty renders IS NULL for eq and IS NOT NULL for
+// neq, and is skipped for other operators, which never match NULL.
+// Untagged fields and `qb:"-"` are ignored; v may be a struct or a pointer to
+// one (a nil pointer adds nothing). A non-struct v and a field with an
+// unknown operator name add nothing and are recorded for BuildErr.
+func (qb *QueryBuilder) WhereStruct(v interface{}) *QueryBuilder {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return qb
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		qb.addErr("WhereStruct expects a struct, got %T", v)
+		return qb
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("qb")
+		if tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		column, op, omitEmpty, valid := parts[0], EQ, false, true
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitEmpty = true
+				continue
+			}
+			o, ok := structOps[opt]
+			if !ok {
+				qb.addErr("unknown operator %q in tag of field %s", opt, field.Name)
+				valid = false
+				break
+			}
+			op = o
+		}
+		if !valid {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			switch op {
+			case EQ:
+				qb.Where(column, NULL, nil)
+			case NEQ:
+				qb.Where(column, NOTNULL, nil)
+			case NULL, NOTNULL:
+				qb.Where(column, op, nil)
+			}
+			continue
+		}
+		value := fv.Interface()
+		if fv.Kind() == reflect.Ptr {
+			value = fv.Elem().Interface()
+		}
+		qb.Where(column, op, value)
+	}
+	return qb
+}
@@ -0,0 +1,107 @@
+package qb
+
+import "slices"
+
+// WhereBuilder accumulates a reusable set of WHERE conditions detached from
+// any query, e.g. a filter fragment shared across queries:
+//
+//	active := NewWhere().Where("deleted_at", NULL, nil).OrWhere("restored", EQ, true)
+//	qb.Select("*").From("users").AttachWhere(active)
+//
+// Placeholders are assigned when the attaching query is built.
+type WhereBuilder struct {
+	qb QueryBuilder
+}
+
+// NewWhere creates an empty WhereBuilder.
+func NewWhere() *WhereBuilder {
+	return &WhereBuilder{}
+}
+
+// Where adds a predicate combined with AND.
+func (w *WhereBuilder) Where(column string, op Operator, value interface{}) *WhereBuilder {
+	w.qb.Where(column, op, value)
+	return w
+}
+
+// OrWhere adds a predicate combined with OR.
+func (w *WhereBuilder) OrWhere(column string, op Operator, value interface{}) *WhereBuilder {
+	w.qb.OrWhere(column, op, value)
+	return w
+}
+
+// WhereIn adds an IN (...) predicate; accepts any slice/array as value.
+func (w *WhereBuilder) WhereIn(column string, value interface{}) *WhereBuilder {
+	w.qb.WhereIn(column, value)
+	return w
+}
+
+// WhereNotIn adds a NOT IN (...) predicate; accepts any slice/array as value.
+func (w *WhereBuilder) WhereNotIn(column string, value interface{}) *WhereBuilder {
+	w.qb.WhereNotIn(column, value)
+	return w
+}
+
+// WhereLike adds a LIKE predicate.
+func (w *WhereBuilder) WhereLike(column, pattern string) *WhereBuilder {
+	w.qb.WhereLike(column, pattern)
+	return w
+}
+
+// WhereNull adds an IS NULL predicate.
+func (w *WhereBuilder) WhereNull(column string) *WhereBuilder {
+	w.qb.WhereNull(column)
+	return w
+}
+
+// WhereNotNull adds an IS NOT NULL predicate.
+func (w *WhereBuilder) WhereNotNull(column string) *WhereBuilder {
+	w.qb.WhereNotNull(column)
+	return w
+}
+
+// Conditions returns a copy of the accumulated conditions.
+func (w *WhereBuilder) Conditions() []Condition {
+	return cloneConditions(w.qb.Conditions)
+}
+
+// AttachWhere appends w's conditions to qb combined with AND. A fragment with
+// more than one condition is wrapped in parentheses so its OR/AND mix keeps
+// its meaning. The fragment is copied and can be attached to other queries.
+func (qb *QueryBuilder) AttachWhere(w *WhereBuilder) *QueryBuilder {
+	qb.andConditions(w.Conditions())
+	return qb
+}
+
+// MergeWhere ANDs other's WHERE conditions into qb, e.g. to apply an
+// access-control policy builder to a user query. Both sides are
+// parenthesized when they hold more than one condition, so
+// "a OR b" merged with "owner_id = x" renders "(a OR b) AND owner_id = $n".
+// other's joins are appended unless qb already has an identical join.
+// Parameters are bound in render order at build time; other is not modified.
+func (qb *QueryBuilder) MergeWhere(other *QueryBuilder) *QueryBuilder {
+	if len(other.Conditions) > 0 && len(qb.Conditions) > 1 {
+		qb.Conditions = []Condition{{Logic: "AND", Group: qb.Conditions}}
+	}
+	qb.andConditions(other.GetConditions())
+
+	for _, j := range other.Joins {
+		if !slices.Contains(qb.Joins, j) {
+			qb.Joins = append(qb.Joins, j)
+		}
+	}
+	return qb
+}
+
+// andConditions appends conds combined with AND, as a group when there is
+// more than one. conds must not be shared with another builder.
+func (qb *QueryBuilder) andConditions(conds []Condition) {
+	switch len(conds) {
+	case 0:
+	case 1:
+		conds[0].Logic = "AND"
+		qb.Conditions = append(qb.Conditions, conds[0])
+	default:
+		qb.Conditions = append(qb.Conditions, Condition{Logic: "AND", Group: conds})
+	}
+}